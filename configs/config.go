@@ -16,6 +16,17 @@ type Config struct {
 	Security  SecurityConfig
 	Logging   LoggingConfig
 	Environment EnvironmentConfig
+	FeatureFlags FeatureFlagConfig
+	Ingest       IngestConfig
+	Trips        TripConfig
+	Anomaly      AnomalyConfig
+	Spatial      SpatialConfig
+	Retention    RetentionConfig
+	Activity     ActivityConfig
+	Privacy      PrivacyConfig
+	Encryption   EncryptionConfig
+	Erasure      ErasureConfig
+	Outbox       OutboxConfig
 }
 
 type DatabaseConfig struct {
@@ -38,6 +49,8 @@ type KafkaConfig struct {
 	Broker   string
 	Topic    string
 	GroupID  string
+	GeofenceTopic string
+	AnomalyTopic  string
 }
 
 type StreamingConfig struct {
@@ -50,6 +63,7 @@ type StreamingConfig struct {
 type SessionConfig struct {
 	DurationSeconds      int
 	SubmissionIntervalSeconds int
+	SubmissionLimiter    string // "memory" or "postgres"
 }
 
 type SecurityConfig struct {
@@ -67,6 +81,98 @@ type EnvironmentConfig struct {
 	Debug       bool
 }
 
+type FeatureFlagConfig struct {
+	TenantServiceURL       string
+	RefreshIntervalSeconds int
+	// Bearer token required to change flags and overrides
+	AdminToken string
+}
+
+type IngestConfig struct {
+	BatchMaxPoints      int
+	MaxClockSkewSeconds int
+	// How far behind server time a single submission may be; 0 is no limit
+	MaxPointAgeSeconds  int
+	ImportMaxMB         int
+	SimplifyMaxPoints   int
+}
+
+// TripConfig holds the thresholds used to segment location streams into
+// trips and stops
+type TripConfig struct {
+	StopRadiusMeters      float64
+	StopMinSeconds        int
+	StopMaxSpeed          float64
+	MaxGapSeconds         int
+	MinTripDistanceMeters float64
+}
+
+// SpatialConfig selects how spatial queries are answered
+type SpatialConfig struct {
+	NearbyBackend     string // "memory" or "postgis"
+	LatestWindowHours int
+}
+
+// ActivityConfig holds the thresholds of the activity rollups
+type ActivityConfig struct {
+	MaxGapSeconds int
+}
+
+// PrivacyConfig locates the privacy policies for services that deliver
+// positions without storing them
+type PrivacyConfig struct {
+	LocationServiceURL     string
+	RefreshIntervalSeconds int
+	// Bearer token services present to read the policies
+	ServiceToken string
+}
+
+// EncryptionConfig selects the key provider holding the master keys that
+// wrap the data keys of stored locations. An empty KeyProvider stores
+// locations in plain text.
+type EncryptionConfig struct {
+	KeyProvider         string // "" or "file"
+	MasterKeyFile       string
+	DataKeyCacheSeconds int
+}
+
+// ErasureConfig controls how soon replicas forget users erased through
+// another replica
+type ErasureConfig struct {
+	PollSeconds int
+}
+
+// OutboxConfig controls the relay publishing stored locations from the
+// outbox table to Kafka
+type OutboxConfig struct {
+	PollIntervalMs     int
+	BatchSize          int
+	MinBackoffSeconds  int
+	MaxBackoffSeconds  int
+	SentRetentionHours int
+}
+
+// RetentionConfig controls the partitions of the locations table and how
+// long points are kept
+type RetentionConfig struct {
+	DefaultDays     int // 0 keeps points forever
+	PremakeMonths   int
+	IntervalMinutes int
+	DeleteBatch     int
+}
+
+// AnomalyConfig holds the thresholds used to flag spoofed or implausible
+// points, and the risk scores that raise alerts or reject points
+type AnomalyConfig struct {
+	MaxSpeedMps           float64
+	TeleportMeters        float64
+	TeleportWindowSeconds int
+	MinAccuracyMeters     float64
+	FutureSeconds         int
+	AlertScore            float64
+	RejectScore           float64
+}
+
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
@@ -88,6 +194,8 @@ func Load() *Config {
 			Broker:  getEnv("KAFKA_BROKER", "localhost:9092"),
 			Topic:   getEnv("KAFKA_TOPIC", "location-stream"),
 			GroupID: getEnv("KAFKA_GROUP_ID", "location-service-group"),
+			GeofenceTopic: getEnv("KAFKA_GEOFENCE_TOPIC", "geofence-events"),
+			AnomalyTopic:  getEnv("KAFKA_ANOMALY_TOPIC", "location-anomalies"),
 		},
 		Streaming: StreamingConfig{
 			Endpoint:      getEnv("STREAMING_ENDPOINT", "http://third-party-streaming-endpoint"),
@@ -98,6 +206,7 @@ func Load() *Config {
 		Session: SessionConfig{
 			DurationSeconds:      getEnvAsInt("SESSION_DURATION_SECONDS", 600),
 			SubmissionIntervalSeconds: getEnvAsInt("SUBMISSION_INTERVAL_SECONDS", 30),
+			SubmissionLimiter:    getEnv("SUBMISSION_LIMITER", "memory"),
 		},
 		Security: SecurityConfig{
 			JWTSecret:        getEnv("JWT_SECRET", "your_jwt_secret_key_here"),
@@ -111,6 +220,67 @@ func Load() *Config {
 			Environment: getEnv("ENVIRONMENT", "development"),
 			Debug:       getEnvAsBool("DEBUG", true),
 		},
+		FeatureFlags: FeatureFlagConfig{
+			TenantServiceURL:       getEnv("TENANT_SERVICE_URL", "http://tenant-service:8080"),
+			RefreshIntervalSeconds: getEnvAsInt("FEATURE_FLAG_REFRESH_SECONDS", 30),
+			AdminToken:             getEnv("FEATURE_FLAGS_ADMIN_TOKEN", ""),
+		},
+		Ingest: IngestConfig{
+			BatchMaxPoints:      getEnvAsInt("LOCATION_BATCH_MAX_POINTS", 100),
+			MaxClockSkewSeconds: getEnvAsInt("LOCATION_MAX_CLOCK_SKEW_SECONDS", 300),
+			MaxPointAgeSeconds:  getEnvAsInt("LOCATION_MAX_POINT_AGE_SECONDS", 0),
+			ImportMaxMB:         getEnvAsInt("LOCATION_IMPORT_MAX_MB", 64),
+			SimplifyMaxPoints:   getEnvAsInt("LOCATION_SIMPLIFY_MAX_POINTS", 100000),
+		},
+		Trips: TripConfig{
+			StopRadiusMeters:      getEnvAsFloat("TRIP_STOP_RADIUS_METERS", 100),
+			StopMinSeconds:        getEnvAsInt("TRIP_STOP_MIN_SECONDS", 300),
+			StopMaxSpeed:          getEnvAsFloat("TRIP_STOP_MAX_SPEED_MPS", 1),
+			MaxGapSeconds:         getEnvAsInt("TRIP_MAX_GAP_SECONDS", 1800),
+			MinTripDistanceMeters: getEnvAsFloat("TRIP_MIN_DISTANCE_METERS", 200),
+		},
+		Anomaly: AnomalyConfig{
+			MaxSpeedMps:           getEnvAsFloat("ANOMALY_MAX_SPEED_MPS", 90),
+			TeleportMeters:        getEnvAsFloat("ANOMALY_TELEPORT_METERS", 100000),
+			TeleportWindowSeconds: getEnvAsInt("ANOMALY_TELEPORT_WINDOW_SECONDS", 900),
+			MinAccuracyMeters:     getEnvAsFloat("ANOMALY_MIN_ACCURACY_METERS", 1),
+			FutureSeconds:         getEnvAsInt("ANOMALY_FUTURE_SECONDS", 30),
+			AlertScore:            getEnvAsFloat("ANOMALY_ALERT_SCORE", 0.5),
+			RejectScore:           getEnvAsFloat("ANOMALY_REJECT_SCORE", 0.8),
+		},
+		Spatial: SpatialConfig{
+			NearbyBackend:     getEnv("NEARBY_BACKEND", "memory"),
+			LatestWindowHours: getEnvAsInt("LATEST_LOCATIONS_WINDOW_HOURS", 168),
+		},
+		Activity: ActivityConfig{
+			MaxGapSeconds: getEnvAsInt("ACTIVITY_MAX_GAP_SECONDS", 300),
+		},
+		Retention: RetentionConfig{
+			DefaultDays:     getEnvAsInt("LOCATION_RETENTION_DAYS", 0),
+			PremakeMonths:   getEnvAsInt("LOCATION_PARTITION_PREMAKE_MONTHS", 3),
+			IntervalMinutes: getEnvAsInt("LOCATION_RETENTION_INTERVAL_MINUTES", 60),
+			DeleteBatch:     getEnvAsInt("LOCATION_RETENTION_DELETE_BATCH", 10000),
+		},
+		Privacy: PrivacyConfig{
+			LocationServiceURL:     getEnv("LOCATION_SERVICE_URL", "http://location-service:8080"),
+			RefreshIntervalSeconds: getEnvAsInt("PRIVACY_POLICY_REFRESH_SECONDS", 30),
+			ServiceToken:           getEnv("PRIVACY_SERVICE_TOKEN", ""),
+		},
+		Encryption: EncryptionConfig{
+			KeyProvider:         getEnv("LOCATION_KEY_PROVIDER", ""),
+			MasterKeyFile:       getEnv("LOCATION_MASTER_KEY_FILE", ""),
+			DataKeyCacheSeconds: getEnvAsInt("LOCATION_DATA_KEY_CACHE_SECONDS", 300),
+		},
+		Erasure: ErasureConfig{
+			PollSeconds: getEnvAsInt("ERASURE_POLL_SECONDS", 30),
+		},
+		Outbox: OutboxConfig{
+			PollIntervalMs:     getEnvAsInt("OUTBOX_POLL_INTERVAL_MS", 500),
+			BatchSize:          getEnvAsInt("OUTBOX_BATCH_SIZE", 500),
+			MinBackoffSeconds:  getEnvAsInt("OUTBOX_MIN_BACKOFF_SECONDS", 1),
+			MaxBackoffSeconds:  getEnvAsInt("OUTBOX_MAX_BACKOFF_SECONDS", 300),
+			SentRetentionHours: getEnvAsInt("OUTBOX_SENT_RETENTION_HOURS", 24),
+		},
 	}
 }
 
@@ -129,6 +299,57 @@ func (c *Config) GetSubmissionInterval() time.Duration {
 	return time.Duration(c.Session.SubmissionIntervalSeconds) * time.Second
 }
 
+// GetFeatureFlagRefreshInterval returns the flag cache refresh interval as time.Duration
+func (c *Config) GetFeatureFlagRefreshInterval() time.Duration {
+	return time.Duration(c.FeatureFlags.RefreshIntervalSeconds) * time.Second
+}
+
+// GetPrivacyRefreshInterval returns the policy cache refresh interval as time.Duration
+func (c *Config) GetPrivacyRefreshInterval() time.Duration {
+	return time.Duration(c.Privacy.RefreshIntervalSeconds) * time.Second
+}
+
+// GetDataKeyCacheTTL returns how long the current data key of a tenant is cached as time.Duration
+func (c *Config) GetDataKeyCacheTTL() time.Duration {
+	return time.Duration(c.Encryption.DataKeyCacheSeconds) * time.Second
+}
+
+// GetErasurePollInterval returns how often replicas check for erased users as time.Duration
+func (c *Config) GetErasurePollInterval() time.Duration {
+	return time.Duration(c.Erasure.PollSeconds) * time.Second
+}
+
+// GetOutboxPollInterval returns how often the outbox relay looks for due entries as time.Duration
+func (c *Config) GetOutboxPollInterval() time.Duration {
+	return time.Duration(c.Outbox.PollIntervalMs) * time.Millisecond
+}
+
+// GetMaxClockSkew returns the tolerated client clock skew as time.Duration
+func (c *Config) GetMaxClockSkew() time.Duration {
+	return time.Duration(c.Ingest.MaxClockSkewSeconds) * time.Second
+}
+
+// GetMaxPointAge returns the age limit of submitted points as time.Duration
+func (c *Config) GetMaxPointAge() time.Duration {
+	return time.Duration(c.Ingest.MaxPointAgeSeconds) * time.Second
+}
+
+// GetLatestLocationsWindow returns how far back the latest-location cache is
+// filled on start as time.Duration
+func (c *Config) GetLatestLocationsWindow() time.Duration {
+	return time.Duration(c.Spatial.LatestWindowHours) * time.Hour
+}
+
+// GetRetentionInterval returns the time between location retention runs
+func (c *Config) GetRetentionInterval() time.Duration {
+	return time.Duration(c.Retention.IntervalMinutes) * time.Minute
+}
+
+// GetImportMaxBytes returns the largest accepted track import upload
+func (c *Config) GetImportMaxBytes() int64 {
+	return int64(c.Ingest.ImportMaxMB) << 20
+}
+
 // Helper functions
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -146,6 +367,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_tenants_status;
+DROP INDEX IF EXISTS idx_tenants_created_at;
+DROP INDEX IF EXISTS idx_tenants_name;
+ALTER TABLE tenants DROP COLUMN IF EXISTS status;
+ALTER TABLE tenants DROP COLUMN IF EXISTS description;
+ALTER TABLE tenants DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,8 @@
+-- Columns used by the tenant-service models and list endpoint
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(255) UNIQUE;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS status VARCHAR(50) NOT NULL DEFAULT 'active';
+-- Keyset pagination on each supported sort order
+CREATE INDEX IF NOT EXISTS idx_tenants_name ON tenants(name, tenant_id);
+CREATE INDEX IF NOT EXISTS idx_tenants_created_at ON tenants(created_at, tenant_id);
+CREATE INDEX IF NOT EXISTS idx_tenants_status ON tenants(status);
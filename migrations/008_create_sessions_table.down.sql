@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_locations_session_id;
+ALTER TABLE locations DROP COLUMN IF EXISTS session_id;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS submission_limits (
+    key VARCHAR(255) PRIMARY KEY,
+    last_submission_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_submission_limits_updated_at ON submission_limits(updated_at);
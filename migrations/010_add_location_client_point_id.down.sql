@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_locations_client_point_id;
+ALTER TABLE locations DROP COLUMN IF EXISTS client_point_id;
+ALTER TABLE locations ALTER COLUMN user_id TYPE INTEGER USING user_id::integer;
+ALTER TABLE locations ADD CONSTRAINT locations_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,6 @@
+-- Users are identified by their Cognito subject rather than users.id
+ALTER TABLE locations DROP CONSTRAINT IF EXISTS locations_user_id_fkey;
+ALTER TABLE locations ALTER COLUMN user_id TYPE VARCHAR(255) USING user_id::text;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS client_point_id VARCHAR(255);
+-- Client-supplied point IDs are unique per tenant and device user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_client_point_id ON locations(tenant_id, user_id, client_point_id) WHERE client_point_id IS NOT NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE locations DROP COLUMN IF EXISTS metadata;
+ALTER TABLE locations DROP COLUMN IF EXISTS provider;
+ALTER TABLE locations DROP COLUMN IF EXISTS battery_level;
+ALTER TABLE locations DROP COLUMN IF EXISTS heading;
+ALTER TABLE locations DROP COLUMN IF EXISTS speed;
+ALTER TABLE locations DROP COLUMN IF EXISTS altitude;
+ALTER TABLE locations DROP COLUMN IF EXISTS accuracy;
@@ -0,0 +1,7 @@
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS accuracy DOUBLE PRECISION;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS altitude DOUBLE PRECISION;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS speed DOUBLE PRECISION;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS heading DOUBLE PRECISION;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS battery_level DOUBLE PRECISION;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS provider VARCHAR(20);
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS metadata JSONB;
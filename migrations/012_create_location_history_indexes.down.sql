@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_locations_tenant_lat_lon;
+DROP INDEX IF EXISTS idx_locations_tenant_session_timestamp;
+DROP INDEX IF EXISTS idx_locations_tenant_user_timestamp;
+DROP INDEX IF EXISTS idx_locations_tenant_timestamp;
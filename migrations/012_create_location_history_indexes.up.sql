@@ -0,0 +1,6 @@
+-- Keyset pagination of location history, per tenant, user and session
+CREATE INDEX IF NOT EXISTS idx_locations_tenant_timestamp ON locations(tenant_id, timestamp, id);
+CREATE INDEX IF NOT EXISTS idx_locations_tenant_user_timestamp ON locations(tenant_id, user_id, timestamp, id);
+CREATE INDEX IF NOT EXISTS idx_locations_tenant_session_timestamp ON locations(tenant_id, session_id, timestamp, id);
+-- Bounding box filters
+CREATE INDEX IF NOT EXISTS idx_locations_tenant_lat_lon ON locations(tenant_id, latitude, longitude);
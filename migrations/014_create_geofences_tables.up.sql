@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS geofences (
+    id VARCHAR(64) PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    center_latitude DOUBLE PRECISION,
+    center_longitude DOUBLE PRECISION,
+    radius_m DOUBLE PRECISION,
+    polygon JSONB,
+    dwell_seconds INTEGER NOT NULL DEFAULT 0,
+    -- Bounding box used to select the fences that may contain a point
+    min_latitude DOUBLE PRECISION NOT NULL,
+    min_longitude DOUBLE PRECISION NOT NULL,
+    max_latitude DOUBLE PRECISION NOT NULL,
+    max_longitude DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_geofences_tenant_name ON geofences(tenant_id, name, id);
+CREATE INDEX IF NOT EXISTS idx_geofences_tenant_bounds ON geofences(tenant_id, min_latitude, max_latitude);
+
+-- Fences each user is currently inside
+CREATE TABLE IF NOT EXISTS geofence_states (
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    geofence_id VARCHAR(64) NOT NULL REFERENCES geofences(id) ON DELETE CASCADE,
+    entered_at TIMESTAMP NOT NULL,
+    dwell_notified BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (tenant_id, user_id, geofence_id)
+);
+
+-- Events outlive their geofence
+CREATE TABLE IF NOT EXISTS geofence_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    geofence_id VARCHAR(64) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    location_id BIGINT,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_tenant_occurred_at ON geofence_events(tenant_id, occurred_at, id);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_tenant_user ON geofence_events(tenant_id, user_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_geofence ON geofence_events(geofence_id, occurred_at);
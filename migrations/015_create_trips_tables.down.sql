@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS trip_segmenter_states;
+DROP TABLE IF EXISTS stops;
+DROP TABLE IF EXISTS trips;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS trips (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP NOT NULL,
+    start_latitude DOUBLE PRECISION NOT NULL,
+    start_longitude DOUBLE PRECISION NOT NULL,
+    end_latitude DOUBLE PRECISION NOT NULL,
+    end_longitude DOUBLE PRECISION NOT NULL,
+    distance_m DOUBLE PRECISION NOT NULL,
+    max_speed_mps DOUBLE PRECISION NOT NULL,
+    point_count INTEGER NOT NULL,
+    polyline TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_trips_tenant_started_at ON trips(tenant_id, started_at, id);
+CREATE INDEX IF NOT EXISTS idx_trips_tenant_user_started_at ON trips(tenant_id, user_id, started_at);
+
+CREATE TABLE IF NOT EXISTS stops (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    point_count INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_stops_tenant_started_at ON stops(tenant_id, started_at, id);
+CREATE INDEX IF NOT EXISTS idx_stops_tenant_user_started_at ON stops(tenant_id, user_id, started_at);
+
+-- Open trip or stop of every user, carried between incremental runs
+CREATE TABLE IF NOT EXISTS trip_segmenter_states (
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    state JSONB NOT NULL,
+    -- Set while a backfill rebuilds the user's trips
+    backfill_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, user_id)
+);
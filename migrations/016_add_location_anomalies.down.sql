@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS anomaly_settings;
+ALTER TABLE locations DROP COLUMN IF EXISTS anomalies;
+ALTER TABLE locations DROP COLUMN IF EXISTS risk_score;
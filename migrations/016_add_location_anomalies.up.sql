@@ -0,0 +1,10 @@
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS risk_score DOUBLE PRECISION;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS anomalies TEXT[];
+
+-- How each tenant handles anomalous points; tenants without a row only flag
+CREATE TABLE IF NOT EXISTS anomaly_settings (
+    tenant_id VARCHAR(255) PRIMARY KEY,
+    mode VARCHAR(20) NOT NULL,
+    reject_score DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_geofences_geog;
+ALTER TABLE geofences DROP COLUMN IF EXISTS geog;
+DROP INDEX IF EXISTS idx_locations_geog;
+ALTER TABLE locations DROP COLUMN IF EXISTS geog;
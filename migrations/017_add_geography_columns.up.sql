@@ -0,0 +1,26 @@
+-- Geography columns are only added where the postgis extension is
+-- available; without them the service answers spatial queries in Go
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'postgis') THEN
+        CREATE EXTENSION IF NOT EXISTS postgis;
+
+        ALTER TABLE locations ADD COLUMN IF NOT EXISTS geog geography(Point, 4326);
+        UPDATE locations SET geog = ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography WHERE geog IS NULL;
+        CREATE INDEX IF NOT EXISTS idx_locations_geog ON locations USING GIST (geog);
+
+        -- Circle centers, or polygons densified so their edges follow the
+        -- planar edges used by the service
+        ALTER TABLE geofences ADD COLUMN IF NOT EXISTS geog geography;
+        UPDATE geofences SET geog = ST_SetSRID(ST_MakePoint(center_longitude, center_latitude), 4326)::geography
+            WHERE geog IS NULL AND type = 'circle';
+        UPDATE geofences SET geog = ST_Segmentize(ST_SetSRID(ST_MakePolygon(ST_AddPoint(ring, ST_StartPoint(ring))), 4326), 0.01)::geography
+            FROM (SELECT id AS ring_id, ST_MakeLine(ARRAY(
+                    SELECT ST_MakePoint((c->>'longitude')::DOUBLE PRECISION, (c->>'latitude')::DOUBLE PRECISION)
+                    FROM jsonb_array_elements(polygon) WITH ORDINALITY AS v(c, n) ORDER BY n)) AS ring
+                FROM geofences WHERE type = 'polygon') rings
+            WHERE geog IS NULL AND id = ring_id;
+        CREATE INDEX IF NOT EXISTS idx_geofences_geog ON geofences USING GIST (geog);
+    END IF;
+END
+$$;
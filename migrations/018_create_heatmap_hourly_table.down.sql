@@ -0,0 +1 @@
+DROP TABLE IF EXISTS heatmap_hourly;
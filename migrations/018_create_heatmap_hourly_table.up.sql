@@ -0,0 +1,10 @@
+-- Points per tenant, hour and zoom 18 Web Mercator cell, maintained on
+-- ingest and import
+CREATE TABLE IF NOT EXISTS heatmap_hourly (
+    tenant_id VARCHAR(255) NOT NULL,
+    hour TIMESTAMP NOT NULL,
+    x INTEGER NOT NULL,
+    y INTEGER NOT NULL,
+    count BIGINT NOT NULL,
+    PRIMARY KEY (tenant_id, hour, x, y)
+);
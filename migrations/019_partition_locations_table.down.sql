@@ -0,0 +1,36 @@
+DROP TABLE IF EXISTS retention_settings;
+DROP TRIGGER IF EXISTS locations_claim_client_point ON locations;
+DROP FUNCTION IF EXISTS claim_location_client_point();
+DROP TABLE IF EXISTS location_client_points;
+
+ALTER TABLE locations RENAME TO locations_partitioned;
+ALTER TABLE locations_partitioned RENAME CONSTRAINT locations_pkey TO locations_partitioned_pkey;
+ALTER SEQUENCE locations_id_seq RENAME TO locations_partitioned_id_seq;
+
+CREATE TABLE locations (LIKE locations_partitioned INCLUDING DEFAULTS);
+ALTER TABLE locations ALTER COLUMN timestamp DROP NOT NULL;
+INSERT INTO locations SELECT * FROM locations_partitioned;
+ALTER SEQUENCE locations_partitioned_id_seq OWNED BY locations.id;
+DROP TABLE locations_partitioned;
+ALTER SEQUENCE locations_partitioned_id_seq RENAME TO locations_id_seq;
+
+ALTER TABLE locations ADD PRIMARY KEY (id);
+ALTER TABLE locations ADD FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE locations ADD FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE SET NULL;
+ALTER TABLE streams ADD CONSTRAINT streams_location_id_fkey FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_locations_tenant_id ON locations(tenant_id);
+CREATE INDEX idx_locations_user_id ON locations(user_id);
+CREATE INDEX idx_locations_session_id ON locations(session_id);
+CREATE UNIQUE INDEX idx_locations_client_point_id ON locations(tenant_id, user_id, client_point_id) WHERE client_point_id IS NOT NULL;
+CREATE INDEX idx_locations_tenant_timestamp ON locations(tenant_id, timestamp, id);
+CREATE INDEX idx_locations_tenant_user_timestamp ON locations(tenant_id, user_id, timestamp, id);
+CREATE INDEX idx_locations_tenant_session_timestamp ON locations(tenant_id, session_id, timestamp, id);
+CREATE INDEX idx_locations_tenant_lat_lon ON locations(tenant_id, latitude, longitude);
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'locations' AND column_name = 'geog') THEN
+        CREATE INDEX idx_locations_geog ON locations USING GIST (geog);
+    END IF;
+END
+$$;
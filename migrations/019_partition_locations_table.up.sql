@@ -0,0 +1,102 @@
+-- locations becomes a table partitioned by month of timestamp. Partitions
+-- are named locations_YYYY_MM; points of months without a partition land in
+-- locations_default until the maintenance job moves them out.
+
+-- A foreign key must reference a unique index, and those of a partitioned
+-- table include the partition key, so streams.location_id can no longer
+-- reference locations(id). The ON DELETE SET NULL it carried is done by the
+-- service instead: retention clears location_id of the points it deletes and
+-- of the partitions it drops.
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS streams_location_id_fkey;
+ALTER TABLE locations RENAME TO locations_unpartitioned;
+ALTER TABLE locations_unpartitioned RENAME CONSTRAINT locations_pkey TO locations_unpartitioned_pkey;
+ALTER SEQUENCE locations_id_seq RENAME TO locations_unpartitioned_id_seq;
+UPDATE locations_unpartitioned SET timestamp = LOCALTIMESTAMP WHERE timestamp IS NULL;
+
+-- Copies every column, including geog where migration 017 added it
+CREATE TABLE locations (LIKE locations_unpartitioned INCLUDING DEFAULTS) PARTITION BY RANGE (timestamp);
+ALTER TABLE locations ALTER COLUMN timestamp SET NOT NULL;
+ALTER TABLE locations ADD PRIMARY KEY (id, timestamp);
+ALTER TABLE locations ADD FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE locations ADD FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE SET NULL;
+CREATE TABLE locations_default PARTITION OF locations DEFAULT;
+
+DO $$
+DECLARE
+    month DATE;
+BEGIN
+    FOR month IN
+        SELECT generate_series(
+            date_trunc('month', LEAST((SELECT MIN(timestamp) FROM locations_unpartitioned), LOCALTIMESTAMP)),
+            date_trunc('month', LOCALTIMESTAMP) + INTERVAL '3 months',
+            INTERVAL '1 month')::DATE
+    LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF locations FOR VALUES FROM (%L) TO (%L)',
+            'locations_' || to_char(month, 'YYYY_MM'), month, (month + INTERVAL '1 month')::DATE);
+    END LOOP;
+END
+$$;
+
+INSERT INTO locations SELECT * FROM locations_unpartitioned;
+ALTER SEQUENCE locations_unpartitioned_id_seq OWNED BY locations.id;
+DROP TABLE locations_unpartitioned;
+ALTER SEQUENCE locations_unpartitioned_id_seq RENAME TO locations_id_seq;
+
+CREATE INDEX idx_locations_tenant_id ON locations(tenant_id);
+CREATE INDEX idx_locations_user_id ON locations(user_id);
+CREATE INDEX idx_locations_session_id ON locations(session_id);
+CREATE INDEX idx_locations_client_point_id ON locations(tenant_id, user_id, client_point_id) WHERE client_point_id IS NOT NULL;
+CREATE INDEX idx_locations_tenant_timestamp ON locations(tenant_id, timestamp, id);
+CREATE INDEX idx_locations_tenant_user_timestamp ON locations(tenant_id, user_id, timestamp, id);
+CREATE INDEX idx_locations_tenant_session_timestamp ON locations(tenant_id, session_id, timestamp, id);
+CREATE INDEX idx_locations_tenant_lat_lon ON locations(tenant_id, latitude, longitude);
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'locations' AND column_name = 'geog') THEN
+        CREATE INDEX idx_locations_geog ON locations USING GIST (geog);
+    END IF;
+END
+$$;
+
+-- Unique indexes of a partitioned table must include the partition key, so
+-- client point IDs are claimed in a table of their own. Inserting a
+-- location whose ID is already claimed is skipped, as ON CONFLICT DO
+-- NOTHING did before.
+CREATE TABLE IF NOT EXISTS location_client_points (
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    client_point_id VARCHAR(255) NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    PRIMARY KEY (tenant_id, user_id, client_point_id)
+);
+CREATE INDEX IF NOT EXISTS idx_location_client_points_tenant_timestamp ON location_client_points(tenant_id, timestamp);
+CREATE INDEX IF NOT EXISTS idx_location_client_points_timestamp ON location_client_points(timestamp);
+INSERT INTO location_client_points (tenant_id, user_id, client_point_id, timestamp)
+    SELECT tenant_id, user_id, client_point_id, timestamp FROM locations WHERE client_point_id IS NOT NULL
+    ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION claim_location_client_point() RETURNS trigger AS $$
+BEGIN
+    IF NEW.client_point_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+    INSERT INTO location_client_points (tenant_id, user_id, client_point_id, timestamp)
+        VALUES (NEW.tenant_id, NEW.user_id, NEW.client_point_id, NEW.timestamp)
+        ON CONFLICT DO NOTHING;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER locations_claim_client_point BEFORE INSERT ON locations
+    FOR EACH ROW EXECUTE FUNCTION claim_location_client_point();
+
+-- How long each tenant keeps its location history; tenants without a row
+-- use LOCATION_RETENTION_DAYS
+CREATE TABLE IF NOT EXISTS retention_settings (
+    tenant_id VARCHAR(255) PRIMARY KEY,
+    retention_days INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
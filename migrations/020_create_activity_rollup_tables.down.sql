@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS activity_daily;
+DROP TABLE IF EXISTS activity_hourly;
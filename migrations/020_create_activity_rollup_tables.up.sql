@@ -0,0 +1,27 @@
+-- Activity of each user per hour of point timestamps. The last position is
+-- kept so the next point's distance can be added incrementally.
+CREATE TABLE IF NOT EXISTS activity_hourly (
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    period_start TIMESTAMP NOT NULL,
+    point_count BIGINT NOT NULL,
+    distance_m DOUBLE PRECISION NOT NULL,
+    active_seconds DOUBLE PRECISION NOT NULL,
+    min_latitude DOUBLE PRECISION NOT NULL,
+    min_longitude DOUBLE PRECISION NOT NULL,
+    max_latitude DOUBLE PRECISION NOT NULL,
+    max_longitude DOUBLE PRECISION NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    last_latitude DOUBLE PRECISION NOT NULL,
+    last_longitude DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (tenant_id, user_id, period_start)
+);
+CREATE INDEX IF NOT EXISTS idx_activity_hourly_tenant_period ON activity_hourly(tenant_id, period_start, user_id);
+
+-- The same per day
+CREATE TABLE IF NOT EXISTS activity_daily (
+    LIKE activity_hourly,
+    PRIMARY KEY (tenant_id, user_id, period_start)
+);
+CREATE INDEX IF NOT EXISTS idx_activity_daily_tenant_period ON activity_daily(tenant_id, period_start, user_id);
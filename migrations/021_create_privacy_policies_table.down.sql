@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS privacy_policies;
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS discarded;
@@ -0,0 +1,13 @@
+ALTER TABLE import_jobs ADD COLUMN IF NOT EXISTS discarded INTEGER NOT NULL DEFAULT 0;
+
+-- Privacy policy of each tenant (user_id '') and of users overriding it
+CREATE TABLE IF NOT EXISTS privacy_policies (
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    coordinate_precision INTEGER,
+    working_hours JSONB,
+    outside_hours VARCHAR(20) NOT NULL DEFAULT '',
+    blur_precision INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, user_id)
+);
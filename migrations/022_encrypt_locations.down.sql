@@ -0,0 +1,7 @@
+-- Sealed locations have no plain coordinates, so this fails until they are
+-- stored in plain text again with `rotate-keys -decrypt`
+ALTER TABLE locations ALTER COLUMN latitude SET NOT NULL;
+ALTER TABLE locations ALTER COLUMN longitude SET NOT NULL;
+ALTER TABLE locations DROP COLUMN IF EXISTS ciphertext;
+ALTER TABLE locations DROP COLUMN IF EXISTS key_version;
+DROP TABLE IF EXISTS tenant_data_keys;
@@ -0,0 +1,16 @@
+-- Sealed locations keep their coordinates and metadata only in ciphertext,
+-- encrypted with version key_version of the tenant's data key
+ALTER TABLE locations ALTER COLUMN latitude DROP NOT NULL;
+ALTER TABLE locations ALTER COLUMN longitude DROP NOT NULL;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS key_version INTEGER;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS ciphertext BYTEA;
+
+-- Data keys of each tenant, wrapped by a master key of the key provider
+CREATE TABLE IF NOT EXISTS tenant_data_keys (
+    tenant_id VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    master_key_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, version)
+);
@@ -0,0 +1,14 @@
+-- Certificates of the erasure of tenant users, kept after their data is gone
+CREATE TABLE IF NOT EXISTS user_erasures (
+    id VARCHAR(64) PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    requested_by VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    deleted JSONB NOT NULL,
+    tombstone_topics TEXT[],
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_erasures_tenant_user ON user_erasures(tenant_id, user_id, status);
+CREATE INDEX IF NOT EXISTS idx_user_erasures_requested_at ON user_erasures(requested_at);
@@ -0,0 +1,17 @@
+-- Locations waiting to be published to Kafka, queued in the transaction
+-- that stores them
+CREATE TABLE IF NOT EXISTS location_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    location_id BIGINT NOT NULL,
+    location_timestamp TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_location_outbox_due ON location_outbox(next_attempt_at, id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_location_outbox_sent_at ON location_outbox(sent_at) WHERE sent_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_location_outbox_tenant_user ON location_outbox(tenant_id, user_id);
@@ -0,0 +1 @@
+ALTER TABLE tenants ALTER COLUMN tenant_id DROP NOT NULL;
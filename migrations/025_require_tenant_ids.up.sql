@@ -0,0 +1,5 @@
+-- Tenants created before 006 have no tenant_id; they take their numeric ID,
+-- which is what users, locations and streams of 002-004 refer to. Listing
+-- pages by (name, tenant_id) and cannot seek past a NULL.
+UPDATE tenants SET tenant_id = id::text WHERE tenant_id IS NULL;
+ALTER TABLE tenants ALTER COLUMN tenant_id SET NOT NULL;
@@ -0,0 +1,54 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes of the response body
+const (
+	CodeValidationFailed = "validation_failed"
+	CodeInvalidRequest   = "invalid_request"
+	CodeLocationRejected = "location_rejected"
+)
+
+// Field error codes
+const (
+	FieldRequired   = "required"
+	FieldOutOfRange = "out_of_range"
+	FieldNotFinite  = "not_finite"
+	FieldTooLong    = "too_long"
+	FieldInvalid    = "invalid"
+	FieldInFuture   = "in_future"
+	FieldTooOld     = "too_old"
+)
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Body is the machine-readable error response shared by all services
+type Body struct {
+	Error  string       `json:"error"`
+	Code   string       `json:"code"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+func New(code, message string, fields ...FieldError) Body {
+	return Body{Error: message, Code: code, Fields: fields}
+}
+
+// Write sends an error body with the given status
+func Write(w http.ResponseWriter, status int, code, message string, fields ...FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(New(code, message, fields...))
+}
+
+// WriteValidation sends a 400 listing the rejected fields
+func WriteValidation(w http.ResponseWriter, fields []FieldError) {
+	Write(w, http.StatusBadRequest, CodeValidationFailed, "Request validation failed", fields...)
+}
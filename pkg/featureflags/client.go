@@ -0,0 +1,263 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Flags rolled out tenant by tenant
+const (
+	Geofencing          = "geofencing"
+	LiveWebSocket       = "live_websocket"
+	ThirdPartyStreaming = "third_party_streaming"
+)
+
+// TenantFeatures is the payload served by GET /tenants/{id}/features
+type TenantFeatures struct {
+	TenantID string          `json:"tenant_id"`
+	Features map[string]bool `json:"features"`
+}
+
+// Client reads evaluated flags from tenant-service. Flags are cached per
+// tenant and fetched in the background, so Enabled never waits longer than
+// firstFetchWait, and only the first time a tenant is seen. When
+// tenant-service cannot be reached the last known value is kept, or the
+// default if there is none, and the fetch is retried after retryAfter.
+// Tenants unknown to tenant-service are not cached, and at most maxTenants
+// tenants are, dropping those unused for idleAfter first.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	defaults   map[string]bool
+
+	mu      sync.RWMutex
+	tenants map[string]*tenantFlags
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// tenantFlags is the cache entry of a tenant
+type tenantFlags struct {
+	features  map[string]bool // nil until a fetch succeeds
+	fetching  bool
+	fetchedAt time.Time     // end of the last fetch
+	usedAt    atomic.Int64  // Unix nanoseconds of the last Enabled call
+	ready     chan struct{} // closed when the first fetch ends
+}
+
+const (
+	// How long Enabled waits for the first fetch of a tenant before
+	// answering with the defaults
+	firstFetchWait = 200 * time.Millisecond
+	// How long a failed fetch is remembered before the next one
+	retryAfter = 5 * time.Second
+	// Tenants not asked about for idleAfter are no longer refreshed
+	idleAfter = time.Hour
+	// Most tenants cached at once; the least recently used makes room
+	maxTenants = 10000
+)
+
+// errUnknownTenant is returned by fetch when tenant-service answers 404
+var errUnknownTenant = errors.New("unknown tenant")
+
+// NewClient starts a client that refreshes cached tenants every
+// refreshInterval. defaults holds the value of each flag when it is unknown.
+func NewClient(baseURL string, refreshInterval time.Duration, defaults map[string]bool) *Client {
+	c := &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		defaults:   defaults,
+		tenants:    make(map[string]*tenantFlags),
+		stop:       make(chan struct{}),
+	}
+	if refreshInterval > 0 {
+		go c.refreshLoop(refreshInterval)
+	}
+	return c
+}
+
+// Enabled reports whether flag is on for tenantID
+func (c *Client) Enabled(ctx context.Context, tenantID, flag string) bool {
+	c.mu.RLock()
+	t, ok := c.tenants[tenantID]
+	due := !ok || t.due()
+	var features map[string]bool
+	if ok {
+		features = t.features
+		t.usedAt.Store(time.Now().UnixNano())
+	}
+	c.mu.RUnlock()
+	if due {
+		t = c.fetchInBackground(tenantID)
+	}
+	if features == nil {
+		timer := time.NewTimer(firstFetchWait)
+		select {
+		case <-t.ready:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+		c.mu.RLock()
+		features = t.features
+		c.mu.RUnlock()
+	}
+	if enabled, ok := features[flag]; ok {
+		return enabled
+	}
+	return c.defaults[flag]
+}
+
+// due reports whether the flags of a tenant are unknown and no fetch has
+// been tried for retryAfter. It is called with c.mu held.
+func (t *tenantFlags) due() bool {
+	return t.features == nil && !t.fetching && time.Since(t.fetchedAt) > retryAfter
+}
+
+// fetchInBackground starts fetching the flags of tenantID unless a fetch is
+// running or has failed recently, and returns the tenant's entry
+func (c *Client) fetchInBackground(tenantID string) *tenantFlags {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tenants[tenantID]
+	if !ok {
+		if len(c.tenants) >= maxTenants {
+			c.evictLeastRecentlyUsed()
+		}
+		t = &tenantFlags{ready: make(chan struct{})}
+		t.usedAt.Store(time.Now().UnixNano())
+		c.tenants[tenantID] = t
+	} else if !t.due() {
+		return t
+	}
+	t.fetching = true
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.httpClient.Timeout)
+		defer cancel()
+		c.load(ctx, tenantID, t)
+	}()
+	return t
+}
+
+// evictLeastRecentlyUsed drops the cached tenant asked about longest ago.
+// It is called with c.mu held.
+func (c *Client) evictLeastRecentlyUsed() {
+	var oldestID string
+	var oldest int64
+	for id, t := range c.tenants {
+		if used := t.usedAt.Load(); oldestID == "" || used < oldest {
+			oldestID, oldest = id, used
+		}
+	}
+	delete(c.tenants, oldestID)
+}
+
+// Refresh reloads the flags of every cached tenant that was asked about in
+// the last idleAfter and drops the others. Tenants with a fetch in progress
+// are skipped.
+func (c *Client) Refresh(ctx context.Context) {
+	idleSince := time.Now().Add(-idleAfter).UnixNano()
+	c.mu.Lock()
+	ids := make([]string, 0, len(c.tenants))
+	entries := make([]*tenantFlags, 0, len(c.tenants))
+	for id, t := range c.tenants {
+		switch {
+		case t.fetching:
+		case t.usedAt.Load() < idleSince:
+			delete(c.tenants, id)
+		default:
+			t.fetching = true
+			ids = append(ids, id)
+			entries = append(entries, t)
+		}
+	}
+	c.mu.Unlock()
+
+	for i, id := range ids {
+		c.load(ctx, id, entries[i])
+	}
+}
+
+// Close stops the background refresh
+func (c *Client) Close() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+func (c *Client) refreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			c.Refresh(ctx)
+			cancel()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// load fetches the flags of a cached tenant, keeping the last known ones
+// when the fetch fails. A tenant unknown to tenant-service is dropped from
+// the cache.
+func (c *Client) load(ctx context.Context, tenantID string, t *tenantFlags) {
+	features, err := c.fetch(ctx, tenantID)
+	if err != nil && !errors.Is(err, errUnknownTenant) {
+		log.Printf("feature flags: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case err == nil:
+		t.features = features
+	case errors.Is(err, errUnknownTenant):
+		t.features = nil
+		if c.tenants[tenantID] == t {
+			delete(c.tenants, tenantID)
+		}
+	}
+	t.fetching = false
+	t.fetchedAt = time.Now()
+	select {
+	case <-t.ready:
+	default:
+		close(t.ready)
+	}
+}
+
+func (c *Client) fetch(ctx context.Context, tenantID string) (map[string]bool, error) {
+	endpoint := c.baseURL + "/tenants/" + url.PathEscape(tenantID) + "/features"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errUnknownTenant
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch flags for tenant %s, status code: %d", tenantID, resp.StatusCode)
+	}
+	var payload TenantFeatures
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	if payload.Features == nil {
+		payload.Features = map[string]bool{}
+	}
+	return payload.Features, nil
+}
@@ -0,0 +1,135 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by the
+// endpoint they are sent to
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Params holds the query parameters shared by all list endpoints:
+// ?limit=&cursor=&sort=&order=
+type Params struct {
+	Limit  int
+	Cursor *Cursor
+	Sort   string
+	Order  string
+}
+
+// Cursor marks the position of the last item of a page. Value is the sort
+// key of that item and ID is its unique identifier, used as a tie-breaker.
+type Cursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// Page is the response envelope returned by list endpoints. Total is left
+// out by endpoints where counting every match would be too expensive.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// ParseQuery reads the common list parameters from q. sortFields lists the
+// accepted values of the sort parameter; the first one is the default.
+func ParseQuery(q url.Values, sortFields ...string) (Params, error) {
+	p := Params{Limit: DefaultLimit, Order: OrderAsc}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return p, fmt.Errorf("invalid limit %q", v)
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+		p.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := DecodeCursor(v)
+		if err != nil {
+			return p, err
+		}
+		p.Cursor = c
+	}
+
+	if len(sortFields) > 0 {
+		p.Sort = sortFields[0]
+	}
+	if v := q.Get("sort"); v != "" {
+		if !contains(sortFields, v) {
+			return p, fmt.Errorf("invalid sort %q, expected one of: %s", v, strings.Join(sortFields, ", "))
+		}
+		p.Sort = v
+	}
+
+	if v := strings.ToLower(q.Get("order")); v != "" {
+		if v != OrderAsc && v != OrderDesc {
+			return p, fmt.Errorf("invalid order %q, expected asc or desc", v)
+		}
+		p.Order = v
+	}
+	return p, nil
+}
+
+// EncodeCursor returns the opaque string form of a cursor
+func EncodeCursor(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Comparator returns the SQL operator used to seek past the cursor for the
+// given order.
+func (p Params) Comparator() string {
+	if p.Order == OrderDesc {
+		return "<"
+	}
+	return ">"
+}
+
+// Direction returns the SQL sort direction for the given order.
+func (p Params) Direction() string {
+	if p.Order == OrderDesc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// TenantPolicies is the payload served by GET /privacy/tenants/{id}: the
+// tenant policy, if any, and the policies of users that have their own
+type TenantPolicies struct {
+	TenantID string             `json:"tenant_id"`
+	Tenant   *Policy            `json:"tenant,omitempty"`
+	Users    map[string]*Policy `json:"users,omitempty"`
+}
+
+// For returns the policy that applies to a user of the tenant, or nil when
+// there is none
+func (tp *TenantPolicies) For(userID string) *Policy {
+	if p, ok := tp.Users[userID]; ok && userID != "" {
+		return p
+	}
+	return tp.Tenant
+}
+
+// Client reads the policies of tenants from location-service for services
+// that deliver positions without storing them. Policies are cached per
+// tenant and refreshed in the background. When location-service cannot be
+// reached the last known policies are kept; a tenant never loaded has no
+// known policy, and Policy returns an error so callers can hold its points
+// back.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+
+	mu      sync.RWMutex
+	tenants map[string]*TenantPolicies
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewClient starts a client that refreshes cached tenants every
+// refreshInterval. token is the service token location-service expects.
+func NewClient(baseURL, token string, refreshInterval time.Duration) *Client {
+	c := &Client{
+		baseURL:    baseURL,
+		token:      token,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		tenants:    make(map[string]*TenantPolicies),
+		stop:       make(chan struct{}),
+	}
+	if refreshInterval > 0 {
+		go c.refreshLoop(refreshInterval)
+	}
+	return c
+}
+
+// Policy returns the policy of a tenant user, or nil when neither the user
+// nor the tenant has one
+func (c *Client) Policy(ctx context.Context, tenantID, userID string) (*Policy, error) {
+	c.mu.RLock()
+	policies, ok := c.tenants[tenantID]
+	c.mu.RUnlock()
+	if !ok {
+		var err error
+		policies, err = c.fetch(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		c.store(tenantID, policies)
+	}
+	return policies.For(userID), nil
+}
+
+// Refresh reloads the policies of every cached tenant
+func (c *Client) Refresh(ctx context.Context) {
+	c.mu.RLock()
+	tenantIDs := make([]string, 0, len(c.tenants))
+	for id := range c.tenants {
+		tenantIDs = append(tenantIDs, id)
+	}
+	c.mu.RUnlock()
+
+	for _, id := range tenantIDs {
+		policies, err := c.fetch(ctx, id)
+		if err != nil {
+			log.Printf("privacy policies: %v", err)
+			continue
+		}
+		c.store(id, policies)
+	}
+}
+
+// Close stops the background refresh
+func (c *Client) Close() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+func (c *Client) refreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			c.Refresh(ctx)
+			cancel()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *Client) store(tenantID string, policies *TenantPolicies) {
+	c.mu.Lock()
+	c.tenants[tenantID] = policies
+	c.mu.Unlock()
+}
+
+func (c *Client) fetch(ctx context.Context, tenantID string) (*TenantPolicies, error) {
+	endpoint := c.baseURL + "/privacy/tenants/" + url.PathEscape(tenantID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch privacy policies for tenant %s, status code: %d", tenantID, resp.StatusCode)
+	}
+	var payload TenantPolicies
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
@@ -0,0 +1,204 @@
+// Package privacy holds the location privacy policies of tenants and users:
+// how precisely positions are kept and what happens to points recorded
+// outside working hours. Every service that stores or delivers positions
+// applies them, so a point looks the same wherever it ends up.
+package privacy
+
+import (
+	"fmt"
+	"math"
+	"time"
+	_ "time/tzdata" // working hours name IANA time zones
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+)
+
+// What happens to points outside working hours
+const (
+	OutsideDrop = "drop"
+	OutsideBlur = "blur"
+)
+
+// MaxPrecision is the largest number of decimal places a policy may keep,
+// about 11 cm of latitude
+const MaxPrecision = 6
+
+// clockLayout is the format of shift start and end times
+const clockLayout = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Policy is the privacy policy of a tenant, or of one of its users when
+// UserID is set. A user policy replaces the tenant policy as a whole.
+// Precision rounds coordinates to that many decimal places; nil keeps them
+// as sent. Without WorkingHours points are tracked at any time, otherwise
+// points outside them are dropped, or blurred to BlurPrecision decimals.
+type Policy struct {
+	TenantID      string        `json:"tenant_id"`
+	UserID        string        `json:"user_id,omitempty"`
+	Precision     *int          `json:"precision,omitempty"`
+	WorkingHours  *WorkingHours `json:"working_hours,omitempty"`
+	OutsideHours  string        `json:"outside_hours,omitempty"`
+	BlurPrecision int           `json:"blur_precision"`
+	UpdatedAt     time.Time     `json:"updated_at,omitempty"`
+}
+
+// WorkingHours are the shifts during which users may be tracked, in the
+// wall clock of TimeZone
+type WorkingHours struct {
+	TimeZone string  `json:"time_zone"`
+	Shifts   []Shift `json:"shifts"`
+}
+
+// Shift is a daily window from Start to End, as HH:MM. An End before Start
+// runs past midnight into the next day. Days lists the weekdays the shift
+// starts on, e.g. "mon"; empty means every day.
+type Shift struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+func (p *Policy) Validate(v *validation.Validator) {
+	if p.Precision != nil && (*p.Precision < 0 || *p.Precision > MaxPrecision) {
+		v.Add("precision", apierror.FieldOutOfRange, fmt.Sprintf("precision must be between 0 and %d", MaxPrecision))
+	}
+	if p.BlurPrecision < 0 || p.BlurPrecision > MaxPrecision {
+		v.Add("blur_precision", apierror.FieldOutOfRange, fmt.Sprintf("blur_precision must be between 0 and %d", MaxPrecision))
+	}
+	if p.WorkingHours == nil {
+		v.OneOf("outside_hours", p.OutsideHours, OutsideDrop, OutsideBlur)
+		return
+	}
+	if v.Required("outside_hours", p.OutsideHours != "") {
+		v.OneOf("outside_hours", p.OutsideHours, OutsideDrop, OutsideBlur)
+	}
+	if v.Required("working_hours.time_zone", p.WorkingHours.TimeZone != "") {
+		if _, err := time.LoadLocation(p.WorkingHours.TimeZone); err != nil {
+			v.Add("working_hours.time_zone", apierror.FieldInvalid, "time_zone must be an IANA time zone, e.g. Europe/Berlin")
+		}
+	}
+	v.Required("working_hours.shifts", len(p.WorkingHours.Shifts) > 0)
+	for i, s := range p.WorkingHours.Shifts {
+		field := fmt.Sprintf("working_hours.shifts[%d]", i)
+		start, startErr := time.Parse(clockLayout, s.Start)
+		if startErr != nil {
+			v.Add(field+".start", apierror.FieldInvalid, "start must be a time of day as HH:MM")
+		}
+		end, endErr := time.Parse(clockLayout, s.End)
+		if endErr != nil {
+			v.Add(field+".end", apierror.FieldInvalid, "end must be a time of day as HH:MM")
+		}
+		if startErr == nil && endErr == nil && start.Equal(end) {
+			v.Add(field+".end", apierror.FieldInvalid, "end must differ from start")
+		}
+		for _, d := range s.Days {
+			if _, ok := weekdays[d]; !ok {
+				v.Add(field+".days", apierror.FieldInvalid, "days must be among sun, mon, tue, wed, thu, fri, sat")
+				break
+			}
+		}
+	}
+}
+
+// Working reports whether t falls inside the working hours. It is true at
+// any time for a nil policy or one without working hours.
+func (p *Policy) Working(t time.Time) bool {
+	if p == nil || p.WorkingHours == nil {
+		return true
+	}
+	loc, err := time.LoadLocation(p.WorkingHours.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	for _, s := range p.WorkingHours.Shifts {
+		start, err1 := time.Parse(clockLayout, s.Start)
+		end, err2 := time.Parse(clockLayout, s.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+		if from < to {
+			if minute >= from && minute < to && s.on(t.Weekday()) {
+				return true
+			}
+			continue
+		}
+		// The shift runs past midnight: the evening belongs to today's
+		// shift, the early morning to yesterday's
+		if minute >= from && s.on(t.Weekday()) {
+			return true
+		}
+		if minute < to && s.on((t.Weekday()+6)%7) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Shift) on(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if weekdays[d] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Drops reports whether a point recorded at t must not be kept at all
+func (p *Policy) Drops(t time.Time) bool {
+	return p != nil && p.OutsideHours == OutsideDrop && !p.Working(t)
+}
+
+// Apply returns the coordinates to keep for a point recorded at t: rounded
+// to the policy precision, and blurred outside working hours. Points that
+// Drops are returned unchanged; callers discard them first.
+func (p *Policy) Apply(lat, lon float64, t time.Time) (float64, float64) {
+	decimals := p.decimals(t)
+	if decimals < 0 {
+		return lat, lon
+	}
+	return Round(lat, decimals), Round(lon, decimals)
+}
+
+// Rounds reports whether Apply changes the coordinates of a point recorded
+// at t, so that the stored point is not the one received
+func (p *Policy) Rounds(t time.Time) bool {
+	return p.decimals(t) >= 0
+}
+
+// decimals returns the decimal places coordinates recorded at t are rounded
+// to, or -1 when they are kept as they are
+func (p *Policy) decimals(t time.Time) int {
+	if p == nil {
+		return -1
+	}
+	decimals := -1
+	if p.Precision != nil {
+		decimals = *p.Precision
+	}
+	if p.OutsideHours == OutsideBlur && !p.Working(t) && (decimals < 0 || p.BlurPrecision < decimals) {
+		decimals = p.BlurPrecision
+	}
+	return decimals
+}
+
+// Round rounds v to the given number of decimal places
+func Round(v float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,115 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+)
+
+// Errors is the list of field errors found while validating a request
+type Errors []apierror.FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator collects field errors. Checks on a nil value are skipped, so
+// optional fields only need to be checked when present.
+type Validator struct {
+	errs Errors
+}
+
+func New() *Validator {
+	return &Validator{}
+}
+
+func (v *Validator) Add(field, code, message string) {
+	v.errs = append(v.errs, apierror.FieldError{Field: field, Code: code, Message: message})
+}
+
+// Required records an error when a required field is missing
+func (v *Validator) Required(field string, present bool) bool {
+	if !present {
+		v.Add(field, apierror.FieldRequired, field+" is required")
+	}
+	return present
+}
+
+// Range checks that a value is finite and within [min, max]
+func (v *Validator) Range(field string, value *float64, min, max float64) {
+	if value == nil {
+		return
+	}
+	if math.IsNaN(*value) || math.IsInf(*value, 0) {
+		v.Add(field, apierror.FieldNotFinite, field+" must be a finite number")
+		return
+	}
+	if *value < min || *value > max {
+		v.Add(field, apierror.FieldOutOfRange, fmt.Sprintf("%s must be between %g and %g", field, min, max))
+	}
+}
+
+// Latitude checks a WGS84 latitude in degrees
+func (v *Validator) Latitude(field string, value *float64) {
+	v.Range(field, value, -90, 90)
+}
+
+// Longitude checks a WGS84 longitude in degrees
+func (v *Validator) Longitude(field string, value *float64) {
+	v.Range(field, value, -180, 180)
+}
+
+// Timestamp bounds a client timestamp against server time. It may be at most
+// maxFuture ahead of now and, when maxPast is positive, at most maxPast
+// behind it.
+func (v *Validator) Timestamp(field string, value *time.Time, now time.Time, maxPast, maxFuture time.Duration) {
+	if value == nil {
+		return
+	}
+	if value.After(now.Add(maxFuture)) {
+		v.Add(field, apierror.FieldInFuture, fmt.Sprintf("%s is more than %s ahead of server time", field, maxFuture))
+		return
+	}
+	if maxPast > 0 && value.Before(now.Add(-maxPast)) {
+		v.Add(field, apierror.FieldTooOld, fmt.Sprintf("%s is more than %s behind server time", field, maxPast))
+	}
+}
+
+func (v *Validator) MaxLength(field, value string, max int) {
+	if len(value) > max {
+		v.Add(field, apierror.FieldTooLong, fmt.Sprintf("%s exceeds %d characters", field, max))
+	}
+}
+
+// OneOf checks that a non-empty value is one of allowed
+func (v *Validator) OneOf(field, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Add(field, apierror.FieldInvalid, fmt.Sprintf("%s must be one of %s", field, strings.Join(allowed, ", ")))
+}
+
+// Errors returns the collected field errors
+func (v *Validator) Errors() Errors {
+	return v.errs
+}
+
+// Err returns the collected errors, or nil if there are none
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
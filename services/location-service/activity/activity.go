@@ -0,0 +1,189 @@
+// Package activity rolls the location stream of each user up into hourly
+// and daily activity summaries.
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// lockScope serializes the rollup updates of a user
+const lockScope = "activity"
+
+// Config holds the rollup thresholds. The time between consecutive points
+// counts as active unless it exceeds MaxGap.
+type Config struct {
+	MaxGap time.Duration
+}
+
+// ConfigFrom converts the environment configuration
+func ConfigFrom(c config.ActivityConfig) Config {
+	return Config{MaxGap: time.Duration(c.MaxGapSeconds) * time.Second}
+}
+
+// accumulator sums points into rollups. The distance and time from one
+// point to the next are counted in the period of the later point; points
+// older than the previous one are counted without them.
+type accumulator struct {
+	cfg      Config
+	tenantID string
+	userID   string
+	prev     *models.ActivityRollup
+	rollups  map[string]map[time.Time]*models.ActivityRollup
+}
+
+func newAccumulator(cfg Config, tenantID, userID string, prev *models.ActivityRollup) *accumulator {
+	return &accumulator{
+		cfg:      cfg,
+		tenantID: tenantID,
+		userID:   userID,
+		prev:     prev,
+		rollups: map[string]map[time.Time]*models.ActivityRollup{
+			models.ActivityHourly: {},
+			models.ActivityDaily:  {},
+		},
+	}
+}
+
+func (a *accumulator) add(l *models.Location) {
+	var distance, active float64
+	if a.prev == nil || !l.Timestamp.Before(a.prev.LastSeen) {
+		if a.prev != nil {
+			distance = geometry.Haversine(
+				geometry.Point{Lat: a.prev.Last.Latitude, Lon: a.prev.Last.Longitude},
+				geometry.Point{Lat: l.Latitude, Lon: l.Longitude})
+			if gap := l.Timestamp.Sub(a.prev.LastSeen); gap <= a.cfg.MaxGap {
+				active = gap.Seconds()
+			}
+		}
+		a.prev = &models.ActivityRollup{LastSeen: l.Timestamp, Last: models.Coordinate{Latitude: l.Latitude, Longitude: l.Longitude}}
+	}
+
+	for granularity, periods := range a.rollups {
+		start := models.ActivityPeriodStart(granularity, l.Timestamp)
+		r, ok := periods[start]
+		if !ok {
+			r = &models.ActivityRollup{
+				TenantID:    a.tenantID,
+				UserID:      a.userID,
+				Granularity: granularity,
+				PeriodStart: start,
+				BBox:        models.ActivityBBox{MinLat: l.Latitude, MinLon: l.Longitude, MaxLat: l.Latitude, MaxLon: l.Longitude},
+				FirstSeen:   l.Timestamp,
+				LastSeen:    l.Timestamp,
+				Last:        models.Coordinate{Latitude: l.Latitude, Longitude: l.Longitude},
+			}
+			periods[start] = r
+		}
+		r.PointCount++
+		r.DistanceMeters += distance
+		r.ActiveSecs += active
+		r.BBox.MinLat = min(r.BBox.MinLat, l.Latitude)
+		r.BBox.MinLon = min(r.BBox.MinLon, l.Longitude)
+		r.BBox.MaxLat = max(r.BBox.MaxLat, l.Latitude)
+		r.BBox.MaxLon = max(r.BBox.MaxLon, l.Longitude)
+		if l.Timestamp.Before(r.FirstSeen) {
+			r.FirstSeen = l.Timestamp
+		}
+		if !l.Timestamp.Before(r.LastSeen) {
+			r.LastSeen = l.Timestamp
+			r.Last = models.Coordinate{Latitude: l.Latitude, Longitude: l.Longitude}
+		}
+	}
+}
+
+func (a *accumulator) result() []*models.ActivityRollup {
+	var out []*models.ActivityRollup
+	for _, periods := range a.rollups {
+		for _, r := range periods {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Granularity != out[j].Granularity {
+			return out[i].Granularity < out[j].Granularity
+		}
+		return out[i].PeriodStart.Before(out[j].PeriodStart)
+	})
+	return out
+}
+
+// Recorder keeps the activity rollups of users up to date
+type Recorder struct {
+	cfg Config
+}
+
+func NewRecorder(cfg Config) *Recorder {
+	return &Recorder{cfg: cfg}
+}
+
+// Record adds newly stored points of a tenant user to their rollups
+func (r *Recorder) Record(ctx context.Context, tenantID, userID string, locations []*models.Location) error {
+	if len(locations) == 0 {
+		return nil
+	}
+	points := make([]*models.Location, len(locations))
+	copy(points, locations)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+
+	return models.WithUserLock(ctx, lockScope, tenantID, userID, func(tx *sql.Tx) error {
+		prev, err := models.LastActivityPoint(ctx, tx, tenantID, userID)
+		if err != nil {
+			return err
+		}
+		acc := newAccumulator(r.cfg, tenantID, userID, prev)
+		for _, l := range points {
+			acc.add(l)
+		}
+		return models.AddActivityRollups(ctx, tx, acc.result())
+	})
+}
+
+// Rebuild recomputes the rollups of a tenant user for the days starting in
+// [from, to) from the stored points, and returns the number of points
+// read. from and to are truncated to days. Points stored for the range
+// while it runs may be missed, so it is meant for past days.
+func (r *Recorder) Rebuild(ctx context.Context, tenantID, userID string, from, to time.Time) (int64, error) {
+	from = models.ActivityPeriodStart(models.ActivityDaily, from)
+	to = models.ActivityPeriodStart(models.ActivityDaily, to)
+
+	// The point before the range starts the first distance
+	before, err := models.QueryLocations(ctx, models.LocationQuery{
+		TenantID: tenantID,
+		UserID:   userID,
+		To:       &from,
+		Params:   pagination.Params{Limit: 1, Order: pagination.OrderDesc},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var prev *models.ActivityRollup
+	if len(before.Items) > 0 {
+		l := before.Items[0]
+		prev = &models.ActivityRollup{LastSeen: l.Timestamp, Last: models.Coordinate{Latitude: l.Latitude, Longitude: l.Longitude}}
+	}
+
+	acc := newAccumulator(r.cfg, tenantID, userID, prev)
+	var n int64
+	err = models.EachLocation(ctx, models.LocationQuery{TenantID: tenantID, UserID: userID, From: &from, To: &to}, func(l models.Location) error {
+		acc.add(&l)
+		n++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, models.WithUserLock(ctx, lockScope, tenantID, userID, func(tx *sql.Tx) error {
+		if err := models.DeleteActivityRollups(ctx, tx, tenantID, userID, from, to); err != nil {
+			return err
+		}
+		return models.AddActivityRollups(ctx, tx, acc.result())
+	})
+}
@@ -0,0 +1,115 @@
+// Package anomaly scores submitted points for signs of GPS spoofing and
+// implausible data.
+package anomaly
+
+import (
+	"math"
+	"time"
+
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// Checks a point can fail
+const (
+	ImpossibleSpeed     = "impossible_speed"
+	Teleport            = "teleport"
+	RepeatedCoordinates = "repeated_coordinates"
+	SuspiciousAccuracy  = "suspicious_accuracy"
+	FutureTimestamp     = "future_timestamp"
+)
+
+// weights is the risk each failed check contributes on its own
+var weights = map[string]float64{
+	ImpossibleSpeed:     0.6,
+	Teleport:            0.8,
+	RepeatedCoordinates: 0.3,
+	SuspiciousAccuracy:  0.4,
+	FutureTimestamp:     0.5,
+}
+
+// Config holds the detection thresholds. Movement between consecutive
+// points faster than MaxSpeed, after allowing for their accuracy, is
+// impossible; a jump of at least TeleportDistance within TeleportWindow is a
+// teleport. Reported accuracy below MinAccuracy and timestamps more than
+// FutureTolerance ahead of server time are suspicious.
+type Config struct {
+	MaxSpeed         float64
+	TeleportDistance float64
+	TeleportWindow   time.Duration
+	MinAccuracy      float64
+	FutureTolerance  time.Duration
+	AlertScore       float64
+}
+
+// ConfigFrom converts the environment configuration
+func ConfigFrom(c config.AnomalyConfig) Config {
+	return Config{
+		MaxSpeed:         c.MaxSpeedMps,
+		TeleportDistance: c.TeleportMeters,
+		TeleportWindow:   time.Duration(c.TeleportWindowSeconds) * time.Second,
+		MinAccuracy:      c.MinAccuracyMeters,
+		FutureTolerance:  time.Duration(c.FutureSeconds) * time.Second,
+		AlertScore:       c.AlertScore,
+	}
+}
+
+// Result is the outcome of checking one point. Score is between 0 and 1.
+type Result struct {
+	Score     float64
+	Anomalies []string
+}
+
+// Check scores l given prev, the user's point before it, or nil when there
+// is none. Checks comparing the two points are skipped when l is not newer
+// than prev. Failed checks combine like independent probabilities, so the
+// score grows with every failure without exceeding 1.
+func Check(cfg Config, prev, l *models.Location, now time.Time) Result {
+	var failed []string
+	if prev != nil && l.Timestamp.After(prev.Timestamp) {
+		elapsed := l.Timestamp.Sub(prev.Timestamp)
+		distance := geometry.Haversine(
+			geometry.Point{Lat: prev.Latitude, Lon: prev.Longitude},
+			geometry.Point{Lat: l.Latitude, Lon: l.Longitude},
+		)
+		// Movement within the accuracy radii may be noise
+		moved := math.Max(0, distance-accuracy(prev)-accuracy(l))
+		if moved/elapsed.Seconds() > cfg.MaxSpeed {
+			failed = append(failed, ImpossibleSpeed)
+		}
+		if distance >= cfg.TeleportDistance && elapsed <= cfg.TeleportWindow {
+			failed = append(failed, Teleport)
+		}
+		// Real receivers jitter; mocked positions repeat to the last digit
+		if l.Latitude == prev.Latitude && l.Longitude == prev.Longitude {
+			failed = append(failed, RepeatedCoordinates)
+		}
+	}
+	if l.Accuracy != nil && *l.Accuracy < cfg.MinAccuracy {
+		failed = append(failed, SuspiciousAccuracy)
+	}
+	if l.Timestamp.After(now.Add(cfg.FutureTolerance)) {
+		failed = append(failed, FutureTimestamp)
+	}
+
+	clean := 1.0
+	for _, name := range failed {
+		clean *= 1 - weights[name]
+	}
+	return Result{Score: math.Round((1-clean)*100) / 100, Anomalies: failed}
+}
+
+// Apply stores the result on l
+func (r Result) Apply(l *models.Location) {
+	score := r.Score
+	l.RiskScore = &score
+	l.Anomalies = r.Anomalies
+}
+
+func accuracy(l *models.Location) float64 {
+	if l.Accuracy == nil {
+		return 0
+	}
+	return *l.Accuracy
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// geohashPrecision is the length of the geohashes indexing positions,
+// cells of about 4.9 by 4.9 km at the equator
+const geohashPrecision = 5
+
+// LatestLocations keeps the most recent location of every user, per tenant.
+// It is filled from the database on start and updated on ingest, so it only
+// reflects points received by this replica after start. Positions are
+// indexed by geohash for proximity search.
+type LatestLocations struct {
+	mu      sync.RWMutex
+	tenants map[string]map[string]models.Location
+	cells   map[string]map[string]map[string]struct{} // tenant, geohash, user IDs
+}
+
+func NewLatestLocations() *LatestLocations {
+	return &LatestLocations{
+		tenants: make(map[string]map[string]models.Location),
+		cells:   make(map[string]map[string]map[string]struct{}),
+	}
+}
+
+// Update records l unless a newer point of the same user is already known
+func (c *LatestLocations) Update(l models.Location) {
+	if l.UserID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	users, ok := c.tenants[l.TenantID]
+	if !ok {
+		users = make(map[string]models.Location)
+		c.tenants[l.TenantID] = users
+	}
+	current, ok := users[l.UserID]
+	if ok && current.Timestamp.After(l.Timestamp) {
+		return
+	}
+	if ok {
+		c.unindex(current)
+	}
+	users[l.UserID] = l
+	c.index(l)
+}
+
+// Get returns the latest location of a single user
+func (c *LatestLocations) Get(tenantID, userID string) (models.Location, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	l, ok := c.tenants[tenantID][userID]
+	return l, ok
+}
+
+// Tenant returns the latest location of every user of a tenant, ordered by
+// user ID
+func (c *LatestLocations) Tenant(tenantID string) []models.Location {
+	c.mu.RLock()
+	users := c.tenants[tenantID]
+	locations := make([]models.Location, 0, len(users))
+	for _, l := range users {
+		locations = append(locations, l)
+	}
+	c.mu.RUnlock()
+	sort.Slice(locations, func(i, j int) bool { return locations[i].UserID < locations[j].UserID })
+	return locations
+}
+
+// Remove forgets a user
+func (c *LatestLocations) Remove(tenantID, userID string) {
+	c.mu.Lock()
+	if l, ok := c.tenants[tenantID][userID]; ok {
+		c.unindex(l)
+		delete(c.tenants[tenantID], userID)
+	}
+	c.mu.Unlock()
+}
+
+// Replace swaps the whole cache content for locations
+func (c *LatestLocations) Replace(locations []models.Location) {
+	tenants := make(map[string]map[string]models.Location)
+	for _, l := range locations {
+		if l.UserID == "" {
+			continue
+		}
+		if tenants[l.TenantID] == nil {
+			tenants[l.TenantID] = make(map[string]models.Location)
+		}
+		tenants[l.TenantID][l.UserID] = l
+	}
+	c.mu.Lock()
+	c.tenants = tenants
+	c.cells = make(map[string]map[string]map[string]struct{})
+	for _, users := range tenants {
+		for _, l := range users {
+			c.index(l)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// maxNearbyCells bounds the cells visited by Nearby; larger searches scan
+// every user of the tenant instead
+const maxNearbyCells = 256
+
+// Nearby returns the latest locations of a tenant's users within
+// radiusMeters of center, closest first, at most limit of them
+func (c *LatestLocations) Nearby(tenantID string, center geometry.Point, radiusMeters float64, limit int) []models.NearbyLocation {
+	c.mu.RLock()
+	users := c.tenants[tenantID]
+	var found []models.NearbyLocation
+	consider := func(l models.Location) {
+		if d := geometry.Haversine(center, geometry.Point{Lat: l.Latitude, Lon: l.Longitude}); d <= radiusMeters {
+			found = append(found, models.NearbyLocation{Location: l, DistanceMeters: d})
+		}
+	}
+	if hashes, ok := geometry.GeohashesWithin(center, radiusMeters, geohashPrecision, maxNearbyCells); ok && len(hashes) < len(users) {
+		cells := c.cells[tenantID]
+		for _, h := range hashes {
+			for userID := range cells[h] {
+				consider(users[userID])
+			}
+		}
+	} else {
+		for _, l := range users {
+			consider(l)
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].DistanceMeters != found[j].DistanceMeters {
+			return found[i].DistanceMeters < found[j].DistanceMeters
+		}
+		return found[i].UserID < found[j].UserID
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found
+}
+
+// index adds l to the geohash index; c.mu must be held for writing
+func (c *LatestLocations) index(l models.Location) {
+	cells, ok := c.cells[l.TenantID]
+	if !ok {
+		cells = make(map[string]map[string]struct{})
+		c.cells[l.TenantID] = cells
+	}
+	h := geometry.EncodeGeohash(geometry.Point{Lat: l.Latitude, Lon: l.Longitude}, geohashPrecision)
+	if cells[h] == nil {
+		cells[h] = make(map[string]struct{})
+	}
+	cells[h][l.UserID] = struct{}{}
+}
+
+// unindex removes l from the geohash index; c.mu must be held for writing
+func (c *LatestLocations) unindex(l models.Location) {
+	cells := c.cells[l.TenantID]
+	h := geometry.EncodeGeohash(geometry.Point{Lat: l.Latitude, Lon: l.Longitude}, geohashPrecision)
+	delete(cells[h], l.UserID)
+	if len(cells[h]) == 0 {
+		delete(cells, h)
+	}
+}
@@ -0,0 +1,68 @@
+// Command backfill-trips rebuilds the trips and stops of a tenant from the
+// stored location history, e.g. after importing tracks or changing the
+// segmentation thresholds:
+//
+//	go run ./services/location-service/cmd/backfill-trips -tenant acme [-user u-1]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/services/location-service/encryption"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+	"github.com/himanshum9/go-mithril/services/location-service/trips"
+)
+
+func main() {
+	tenantID := flag.String("tenant", "", "tenant ID (required)")
+	userID := flag.String("user", "", "only rebuild this user (default: every user of the tenant)")
+	flag.Parse()
+	if *tenantID == "" {
+		fmt.Fprintln(os.Stderr, "usage: backfill-trips -tenant ID [-user ID]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	connStr := os.Getenv("LOCATION_DB_CONN")
+	if connStr == "" {
+		connStr = cfg.GetDatabaseURL()
+	}
+	if err := models.InitDB(connStr); err != nil {
+		log.Fatalf("DB connection failed: %v", err)
+	}
+	keyProvider, err := encryption.ProviderFrom(cfg.Encryption)
+	if err != nil {
+		log.Fatalf("Failed to open key provider: %v", err)
+	}
+	models.EnableEncryption(keyProvider, cfg.GetDataKeyCacheTTL())
+
+	ctx := context.Background()
+	users := []string{*userID}
+	if *userID == "" {
+		var err error
+		if users, err = models.ListLocationUsers(ctx, *tenantID); err != nil {
+			log.Fatalf("Failed to list users: %v", err)
+		}
+	}
+
+	processor := trips.NewProcessor(trips.ConfigFrom(cfg.Trips))
+	failed := 0
+	for _, u := range users {
+		nTrips, nStops, err := processor.Backfill(ctx, *tenantID, u)
+		if err != nil {
+			log.Printf("user %s: %v", u, err)
+			failed++
+			continue
+		}
+		fmt.Printf("user %s: %d trips, %d stops\n", u, nTrips, nStops)
+	}
+	if failed > 0 {
+		log.Fatalf("Backfill failed for %d of %d users", failed, len(users))
+	}
+}
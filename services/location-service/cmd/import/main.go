@@ -0,0 +1,105 @@
+// Command import loads a historical track file into the locations table,
+// the offline counterpart of POST /locations/import:
+//
+//	go run ./services/location-service/cmd/import -tenant acme -user u-1 track.gpx
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/services/location-service/encryption"
+	"github.com/himanshum9/go-mithril/services/location-service/importer"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+func main() {
+	tenantID := flag.String("tenant", "", "tenant ID (required)")
+	userID := flag.String("user", "", "user the points belong to (required)")
+	formatName := flag.String("format", "", "file format: "+strings.Join(importer.Names(), ", ")+" (default: from the file extension)")
+	replay := flag.Bool("replay", false, "queue imported points for publishing to Kafka")
+	batchSize := flag.Int("batch", importer.DefaultBatchSize, "points copied per statement")
+	flag.Parse()
+	if *tenantID == "" || *userID == "" || flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: import -tenant ID -user ID [-format NAME] [-replay] FILE")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	var format importer.Format
+	var ok bool
+	if *formatName != "" {
+		format, ok = importer.Lookup(*formatName)
+	} else {
+		format, ok = importer.Detect(path, "")
+	}
+	if !ok {
+		log.Fatalf("Unsupported import format, expected one of: %s", strings.Join(importer.Names(), ", "))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Fatalf("Failed to stat %s: %v", path, err)
+	}
+
+	cfg := config.Load()
+	connStr := os.Getenv("LOCATION_DB_CONN")
+	if connStr == "" {
+		connStr = cfg.GetDatabaseURL()
+	}
+	if err := models.InitDB(connStr); err != nil {
+		log.Fatalf("DB connection failed: %v", err)
+	}
+	keyProvider, err := encryption.ProviderFrom(cfg.Encryption)
+	if err != nil {
+		log.Fatalf("Failed to open key provider: %v", err)
+	}
+	models.EnableEncryption(keyProvider, cfg.GetDataKeyCacheTTL())
+
+	policy, err := models.EffectivePrivacyPolicy(context.Background(), *tenantID, *userID)
+	if err != nil {
+		log.Fatalf("Failed to load privacy policy: %v", err)
+	}
+
+	opts := importer.Options{
+		BatchSize:    *batchSize,
+		MaxClockSkew: cfg.GetMaxClockSkew(),
+		Privacy:      policy,
+		Progress: func(job *models.ImportJob) {
+			fmt.Fprintf(os.Stderr, "\r%3.0f%%  %d processed, %d imported, %d duplicates, %d discarded, %d failed",
+				100*float64(job.ReadBytes)/float64(max(job.SizeBytes, 1)), job.Processed, job.Imported, job.Duplicates, job.Discarded, job.Failed)
+		},
+	}
+	job := models.NewImportJob(*tenantID, *userID, format.Name, *replay, info.Size())
+	if err := models.SaveImportJob(context.Background(), job); err != nil {
+		log.Fatalf("Failed to create import job: %v", err)
+	}
+	err = importer.Run(context.Background(), job, format, file, opts)
+	fmt.Fprintln(os.Stderr)
+	for _, e := range job.Errors {
+		if e.Error != "" {
+			fmt.Printf("line %d: %s\n", e.Line, e.Error)
+		}
+		for _, f := range e.Fields {
+			fmt.Printf("line %d: %s\n", e.Line, f.Message)
+		}
+	}
+	if job.Failed > len(job.Errors) {
+		fmt.Printf("... %d more rejected points\n", job.Failed-len(job.Errors))
+	}
+	if err != nil {
+		log.Fatalf("Import job %s failed: %v", job.ID, err)
+	}
+	fmt.Printf("Import job %s: %d imported, %d duplicates, %d discarded, %d failed\n", job.ID, job.Imported, job.Duplicates, job.Discarded, job.Failed)
+}
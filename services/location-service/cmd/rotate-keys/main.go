@@ -0,0 +1,116 @@
+// Command rotate-keys maintains the envelope encryption of stored
+// locations. It seals the locations of tenants with their current data key,
+// rotating data keys, the master key, or both first when asked, and can
+// store all locations in plain text again before encryption is turned off:
+//
+//	go run ./services/location-service/cmd/rotate-keys [-tenant acme] [-rotate] [-new-master | -rewrap] [-batch 1000]
+//	go run ./services/location-service/cmd/rotate-keys -decrypt
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/services/location-service/encryption"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+func main() {
+	tenantID := flag.String("tenant", "", "tenant ID (default: every tenant with locations)")
+	rotate := flag.Bool("rotate", false, "create a new data key for each tenant before re-encrypting")
+	newMaster := flag.Bool("new-master", false, "add a new current master key to LOCATION_MASTER_KEY_FILE and rewrap all data keys with it")
+	rewrap := flag.Bool("rewrap", false, "rewrap data keys not wrapped with the current master key")
+	decrypt := flag.Bool("decrypt", false, "store locations in plain text again")
+	batchSize := flag.Int("batch", 1000, "locations rewritten per transaction")
+	flag.Parse()
+	if *batchSize <= 0 || (*decrypt && (*rotate || *newMaster)) {
+		fmt.Fprintln(os.Stderr, "usage: rotate-keys [-tenant ID] [-rotate] [-new-master | -rewrap] [-batch N] | [-tenant ID] -decrypt")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	if *newMaster {
+		if cfg.Encryption.KeyProvider != "file" {
+			log.Fatalf("-new-master needs LOCATION_KEY_PROVIDER=file; rotate the master key in the KMS and use -rewrap")
+		}
+		id, err := encryption.AddFileKey(cfg.Encryption.MasterKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to add master key: %v", err)
+		}
+		fmt.Printf("Added master key %s to %s\n", id, cfg.Encryption.MasterKeyFile)
+	}
+	provider, err := encryption.ProviderFrom(cfg.Encryption)
+	if err != nil {
+		log.Fatalf("Failed to open key provider: %v", err)
+	}
+	if provider == nil {
+		log.Fatalf("LOCATION_KEY_PROVIDER is not set")
+	}
+
+	connStr := os.Getenv("LOCATION_DB_CONN")
+	if connStr == "" {
+		connStr = cfg.GetDatabaseURL()
+	}
+	if err := models.InitDB(connStr); err != nil {
+		log.Fatalf("DB connection failed: %v", err)
+	}
+	models.EnableEncryption(provider, cfg.GetDataKeyCacheTTL())
+
+	ctx := context.Background()
+	if *newMaster || *rewrap {
+		n, err := models.RewrapDataKeys(ctx)
+		if err != nil {
+			log.Fatalf("Failed to rewrap data keys after %d: %v", n, err)
+		}
+		fmt.Printf("Rewrapped %d data keys with master key %s\n", n, provider.CurrentKeyID())
+	}
+
+	tenants := []string{*tenantID}
+	if *tenantID == "" {
+		if tenants, err = models.ListLocationTenants(ctx); err != nil {
+			log.Fatalf("Failed to list tenants: %v", err)
+		}
+	}
+	if *rotate {
+		for _, id := range tenants {
+			version, err := models.RotateDataKey(ctx, id)
+			if err != nil {
+				log.Fatalf("Failed to rotate the data key of tenant %s: %v", id, err)
+			}
+			fmt.Printf("tenant %s: data key version %d\n", id, version)
+		}
+	}
+	rotatedAt := time.Now()
+	reencrypt(ctx, tenants, *decrypt, *batchSize)
+
+	// Replicas keep sealing with the previous data key until their cache
+	// expires; a second pass picks up what they stored meanwhile
+	if *rotate {
+		if wait := cfg.GetDataKeyCacheTTL() - time.Since(rotatedAt); wait > 0 {
+			fmt.Printf("Waiting %s for replicas to switch to the new data keys\n", wait.Round(time.Second))
+			time.Sleep(wait)
+		}
+		reencrypt(ctx, tenants, *decrypt, *batchSize)
+	}
+}
+
+func reencrypt(ctx context.Context, tenants []string, decrypt bool, batchSize int) {
+	for _, id := range tenants {
+		n, err := models.ReencryptLocations(ctx, id, decrypt, batchSize, func(n int64) {
+			fmt.Fprintf(os.Stderr, "\rtenant %s: %d locations", id, n)
+		})
+		if n > 0 {
+			fmt.Fprintln(os.Stderr)
+		}
+		if err != nil {
+			log.Fatalf("Failed to re-encrypt the locations of tenant %s after %d: %v", id, n, err)
+		}
+		fmt.Printf("tenant %s: %d locations rewritten\n", id, n)
+	}
+}
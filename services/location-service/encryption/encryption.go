@@ -0,0 +1,118 @@
+// Package encryption seals stored positions with envelope encryption. Each
+// tenant has random AES-256 data keys, stored wrapped by a master key that
+// never leaves its key provider; only the provider can unwrap them.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	config "github.com/himanshum9/go-mithril/configs"
+)
+
+// DataKeySize is the length of data and master keys, for AES-256
+const DataKeySize = 32
+
+var errShortCiphertext = errors.New("ciphertext too short")
+
+// KeyProvider holds master keys and wraps data keys with them, like a KMS
+type KeyProvider interface {
+	// Wrap encrypts a data key with the current master key and returns it
+	// with the ID of that master key
+	Wrap(ctx context.Context, dataKey []byte) (wrapped []byte, masterKeyID string, err error)
+	// Unwrap decrypts a data key wrapped with the given master key
+	Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error)
+	// CurrentKeyID returns the ID of the master key new data keys are
+	// wrapped with
+	CurrentKeyID() string
+}
+
+// Opener creates a key provider from the configuration
+type Opener func(cfg config.EncryptionConfig) (KeyProvider, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Opener{
+		"file": func(cfg config.EncryptionConfig) (KeyProvider, error) {
+			return NewFileProvider(cfg.MasterKeyFile)
+		},
+	}
+)
+
+// Register makes a key provider available under name, e.g. for a cloud KMS
+func Register(name string, open Opener) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = open
+}
+
+// ProviderFrom opens the key provider named by LOCATION_KEY_PROVIDER, or
+// returns nil when encryption is off
+func ProviderFrom(cfg config.EncryptionConfig) (KeyProvider, error) {
+	if cfg.KeyProvider == "" {
+		return nil, nil
+	}
+	providersMu.RLock()
+	open, ok := providers[cfg.KeyProvider]
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	providersMu.RUnlock()
+	if !ok {
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown key provider %q, expected one of: %s", cfg.KeyProvider, strings.Join(names, ", "))
+	}
+	return open(cfg)
+}
+
+// NewDataKey returns a random data key
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, DataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext with AES-GCM under key, binding it to
+// additionalData, and returns the nonce followed by the ciphertext
+func Seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts what Seal returned for the same key and additionalData
+func Open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errShortCiphertext
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,115 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// keyFile is the JSON layout of a master key file:
+//
+//	{"current": "k20240101T000000", "keys": {"k20240101T000000": "<base64 of 32 bytes>"}}
+type keyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// FileProvider keeps master keys in a local JSON file. It is meant for
+// development and tests; production deployments register a KMS instead.
+type FileProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewFileProvider loads the master keys of the file at path
+func NewFileProvider(path string) (*FileProvider, error) {
+	if path == "" {
+		return nil, errors.New("LOCATION_MASTER_KEY_FILE is not set")
+	}
+	f, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &FileProvider{current: f.Current, keys: make(map[string][]byte, len(f.Keys))}
+	for id, encoded := range f.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != DataKeySize {
+			return nil, fmt.Errorf("master key %s in %s is not %d base64 bytes", id, path, DataKeySize)
+		}
+		p.keys[id] = key
+	}
+	if _, ok := p.keys[p.current]; !ok {
+		return nil, fmt.Errorf("current master key %q not found in %s", p.current, path)
+	}
+	return p, nil
+}
+
+func (p *FileProvider) Wrap(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	wrapped, err := Seal(p.keys[p.current], dataKey, []byte(p.current))
+	return wrapped, p.current, err
+}
+
+func (p *FileProvider) Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %s not found", masterKeyID)
+	}
+	return Open(key, wrapped, []byte(masterKeyID))
+}
+
+func (p *FileProvider) CurrentKeyID() string {
+	return p.current
+}
+
+// AddFileKey generates a master key and makes it the current key of the
+// file at path, creating the file if needed. Earlier keys are kept so data
+// keys wrapped with them can still be unwrapped. It returns the new key ID.
+func AddFileKey(path string) (string, error) {
+	f, err := readKeyFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		f, err = &keyFile{}, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if f.Keys == nil {
+		f.Keys = map[string]string{}
+	}
+	key, err := NewDataKey()
+	if err != nil {
+		return "", err
+	}
+	id := "k" + time.Now().UTC().Format("20060102T150405")
+	if _, exists := f.Keys[id]; exists {
+		return "", fmt.Errorf("master key %s already exists", id)
+	}
+	f.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	f.Current = id
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	// Replace the file in one step so a crash never leaves it half written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return "", err
+	}
+	return id, os.Rename(tmp, path)
+}
+
+func readKeyFile(path string) (*keyFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &f, nil
+}
@@ -0,0 +1,48 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+var csvHeader = []string{"id", "user_id", "session_id", "timestamp", "latitude", "longitude", "accuracy", "altitude", "speed", "heading", "battery_level", "provider"}
+
+// WriteCSV writes one row per location with a header row
+func WriteCSV(w io.Writer, name string, src Source) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	err := src(func(l models.Location) error {
+		return cw.Write([]string{
+			strconv.FormatInt(l.ID, 10),
+			l.UserID,
+			l.SessionID,
+			l.Timestamp.UTC().Format(time.RFC3339),
+			formatFloat(l.Latitude),
+			formatFloat(l.Longitude),
+			optionalFloat(l.Accuracy),
+			optionalFloat(l.Altitude),
+			optionalFloat(l.Speed),
+			optionalFloat(l.Heading),
+			optionalFloat(l.BatteryLevel),
+			l.Provider,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func optionalFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return formatFloat(*f)
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// Source calls fn for every exported point in track order. Formats that
+// need the points twice, e.g. as a line and as individual features, call it
+// again rather than hold the track in memory.
+type Source func(fn func(models.Location) error) error
+
+// Format encodes a track to w
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	Write       func(w io.Writer, name string, src Source) error
+}
+
+var formats = []Format{
+	{Name: "geojson", ContentType: "application/geo+json", Extension: "geojson", Write: WriteGeoJSON},
+	{Name: "gpx", ContentType: "application/gpx+xml", Extension: "gpx", Write: WriteGPX},
+	{Name: "kml", ContentType: "application/vnd.google-earth.kml+xml", Extension: "kml", Write: WriteKML},
+	{Name: "csv", ContentType: "text/csv", Extension: "csv", Write: WriteCSV},
+}
+
+// Lookup returns the format with the given name
+func Lookup(name string) (Format, bool) {
+	for _, f := range formats {
+		if f.Name == strings.ToLower(name) {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// Negotiate picks the first format of an Accept header that is supported.
+// A missing header or */* selects GeoJSON.
+func Negotiate(accept string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return formats[0], true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == "*/*" || mediaType == "application/json" {
+			return formats[0], true
+		}
+		for _, f := range formats {
+			if mediaType == f.ContentType {
+				return f, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// Names lists the supported format names
+func Names() []string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.Name
+	}
+	return names
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// WriteGeoJSON writes a FeatureCollection holding one Point feature per
+// location followed by the track as a LineString, and the bbox of the
+// track. The points are read twice, for the features and for the line, so
+// neither is held in memory.
+func WriteGeoJSON(w io.Writer, name string, src Source) error {
+	bw := bufio.NewWriter(w)
+	nameJSON, _ := json.Marshal(name)
+	fmt.Fprintf(bw, `{"type":"FeatureCollection","name":%s,"features":[`, nameJSON)
+
+	err := src(func(l models.Location) error {
+		props, err := json.Marshal(pointProperties(l))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, `{"type":"Feature","properties":%s,"geometry":{"type":"Point","coordinates":%s}},`, props, position(l))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(bw, `{"type":"Feature","properties":{"name":%s},"geometry":{"type":"LineString","coordinates":[`, nameJSON)
+	first := true
+	var minLon, minLat, maxLon, maxLat float64
+	err = src(func(l models.Location) error {
+		if first {
+			minLon, minLat, maxLon, maxLat = l.Longitude, l.Latitude, l.Longitude, l.Latitude
+		} else {
+			bw.WriteByte(',')
+			minLon, maxLon = min(minLon, l.Longitude), max(maxLon, l.Longitude)
+			minLat, maxLat = min(minLat, l.Latitude), max(maxLat, l.Latitude)
+		}
+		first = false
+		bw.WriteString(position(l))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString(`]}}]`)
+	if !first {
+		fmt.Fprintf(bw, `,"bbox":[%s,%s,%s,%s]`, formatFloat(minLon), formatFloat(minLat), formatFloat(maxLon), formatFloat(maxLat))
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// position returns a GeoJSON position, longitude first
+func position(l models.Location) string {
+	pos := "[" + formatFloat(l.Longitude) + "," + formatFloat(l.Latitude)
+	if l.Altitude != nil {
+		pos += "," + formatFloat(*l.Altitude)
+	}
+	return pos + "]"
+}
+
+type featureProperties struct {
+	ID        int64  `json:"id"`
+	UserID    string `json:"user_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Timestamp string `json:"timestamp"`
+	models.Telemetry
+}
+
+func pointProperties(l models.Location) featureProperties {
+	return featureProperties{
+		ID:        l.ID,
+		UserID:    l.UserID,
+		SessionID: l.SessionID,
+		Timestamp: l.Timestamp.UTC().Format(time.RFC3339),
+		Telemetry: l.Telemetry,
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// WriteGPX writes a GPX 1.1 track with one segment per tracking session
+func WriteGPX(w io.Writer, name string, src Source) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<gpx version="1.1" creator="go-mithril" xmlns="http://www.topografix.com/GPX/1/1">` + "\n")
+	fmt.Fprintf(bw, "<trk><name>%s</name>\n", escapeXML(name))
+
+	open := false
+	session := ""
+	err := src(func(l models.Location) error {
+		if !open || l.SessionID != session {
+			if open {
+				bw.WriteString("</trkseg>\n")
+			}
+			bw.WriteString("<trkseg>\n")
+			open = true
+			session = l.SessionID
+		}
+		fmt.Fprintf(bw, `<trkpt lat="%s" lon="%s">`, formatFloat(l.Latitude), formatFloat(l.Longitude))
+		if l.Altitude != nil {
+			fmt.Fprintf(bw, "<ele>%s</ele>", formatFloat(*l.Altitude))
+		}
+		fmt.Fprintf(bw, "<time>%s</time>", l.Timestamp.UTC().Format(time.RFC3339))
+		bw.WriteString("</trkpt>\n")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if open {
+		bw.WriteString("</trkseg>\n")
+	}
+	bw.WriteString("</trk>\n</gpx>\n")
+	return bw.Flush()
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// WriteKML writes a KML document with a folder of time-stamped point
+// placemarks followed by the track as a LineString placemark. The points are
+// read twice, for the placemarks and for the line, so neither is held in
+// memory.
+func WriteKML(w io.Writer, name string, src Source) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	bw.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2"><Document>` + "\n")
+	fmt.Fprintf(bw, "<name>%s</name>\n", escapeXML(name))
+
+	bw.WriteString("<Folder><name>Points</name>\n")
+	err := src(func(l models.Location) error {
+		ts := l.Timestamp.UTC().Format(time.RFC3339)
+		fmt.Fprintf(bw, "<Placemark><name>%s</name><TimeStamp><when>%s</when></TimeStamp><Point><coordinates>%s</coordinates></Point></Placemark>\n",
+			ts, ts, kmlCoordinates(l))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("</Folder>\n")
+
+	fmt.Fprintf(bw, "<Placemark><name>%s</name><LineString><tessellate>1</tessellate><coordinates>\n", escapeXML(name))
+	err = src(func(l models.Location) error {
+		bw.WriteString(kmlCoordinates(l) + "\n")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("</coordinates></LineString></Placemark>\n")
+	bw.WriteString("</Document></kml>\n")
+	return bw.Flush()
+}
+
+func kmlCoordinates(l models.Location) string {
+	c := formatFloat(l.Longitude) + "," + formatFloat(l.Latitude)
+	if l.Altitude != nil {
+		c += "," + formatFloat(*l.Altitude)
+	}
+	return c
+}
@@ -0,0 +1,88 @@
+package geometry
+
+import "math"
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// EncodeGeohash returns the geohash of p with precision characters
+func EncodeGeohash(p Point, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	hash := make([]byte, 0, precision)
+	bits, ch := 0, 0
+	even := true
+	for len(hash) < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			ch <<= 1
+			if p.Lon >= mid {
+				ch |= 1
+				minLon = mid
+			} else {
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			ch <<= 1
+			if p.Lat >= mid {
+				ch |= 1
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+		even = !even
+		if bits++; bits == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// GeohashCellSize returns the height and width in degrees of the cells of
+// geohashes with precision characters
+func GeohashCellSize(precision int) (float64, float64) {
+	bits := 5 * precision
+	lonBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Exp2(float64(latBits)), 360 / math.Exp2(float64(lonBits))
+}
+
+// GeohashesWithin returns the geohashes with precision characters of the
+// cells that may hold points within radiusMeters of center. The second
+// result is false when that would be more than max cells.
+func GeohashesWithin(center Point, radiusMeters float64, precision, max int) ([]string, bool) {
+	cellLat, cellLon := GeohashCellSize(precision)
+	dLat := MetersToDegreesLat(radiusMeters)
+	minLat := math.Max(-90, center.Lat-dLat)
+	maxLat := math.Min(90, center.Lat+dLat)
+
+	// The radius spans the most longitude at the edge nearest a pole, and
+	// every longitude around the poles
+	dLon := math.Min(180, MetersToDegreesLon(radiusMeters, math.Max(math.Abs(minLat), math.Abs(maxLat))))
+
+	firstRow := math.Floor((minLat + 90) / cellLat)
+	lastRow := math.Min(math.Floor((maxLat+90)/cellLat), 180/cellLat-1)
+	firstCol := math.Floor((center.Lon - dLon + 180) / cellLon)
+	lastCol := math.Floor((center.Lon + dLon + 180) / cellLon)
+	cols := 360 / cellLon
+	if lastCol-firstCol+1 > cols {
+		firstCol, lastCol = 0, cols-1
+	}
+	if (lastRow-firstRow+1)*(lastCol-firstCol+1) > float64(max) {
+		return nil, false
+	}
+
+	var hashes []string
+	for row := firstRow; row <= lastRow; row++ {
+		lat := -90 + (row+0.5)*cellLat
+		for col := firstCol; col <= lastCol; col++ {
+			// Columns past the antimeridian wrap around
+			c := math.Mod(math.Mod(col, cols)+cols, cols)
+			lon := -180 + (c+0.5)*cellLon
+			hashes = append(hashes, EncodeGeohash(Point{Lat: lat, Lon: lon}, precision))
+		}
+	}
+	return hashes, true
+}
@@ -0,0 +1,112 @@
+package geometry
+
+import "testing"
+
+// geohashBounds decodes a geohash into the south-west and north-east
+// corners of its cell
+func geohashBounds(hash string) (Point, Point) {
+	sw, ne := Point{Lat: -90, Lon: -180}, Point{Lat: 90, Lon: 180}
+	even := true
+	for _, c := range hash {
+		v := -1
+		for i := range geohashAlphabet {
+			if rune(geohashAlphabet[i]) == c {
+				v = i
+			}
+		}
+		for bit := 4; bit >= 0; bit-- {
+			on := v>>bit&1 == 1
+			if even {
+				mid := (sw.Lon + ne.Lon) / 2
+				if on {
+					sw.Lon = mid
+				} else {
+					ne.Lon = mid
+				}
+			} else {
+				mid := (sw.Lat + ne.Lat) / 2
+				if on {
+					sw.Lat = mid
+				} else {
+					ne.Lat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return sw, ne
+}
+
+func TestEncodeGeohash(t *testing.T) {
+	tests := []struct {
+		name      string
+		p         Point
+		precision int
+		want      string
+	}{
+		{"Jutland reference point", Point{Lat: 57.64911, Lon: 10.40744}, 11, "u4pruydqqvj"},
+		{"origin starts the north-east cell", Point{Lat: 0, Lon: 0}, 5, "s0000"},
+		{"just south-west of the origin", Point{Lat: -1e-9, Lon: -1e-9}, 5, "7zzzz"},
+		{"south-west corner of the world", Point{Lat: -90, Lon: -180}, 5, "00000"},
+		{"north-east corner of the world", Point{Lat: 90, Lon: 180}, 5, "zzzzz"},
+		{"west of the antimeridian", Point{Lat: 0, Lon: 179.99}, 3, "xbp"},
+		{"east of the antimeridian", Point{Lat: 0, Lon: -179.99}, 3, "800"},
+		{"no precision", Point{Lat: 10, Lon: 10}, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncodeGeohash(tt.p, tt.precision); got != tt.want {
+				t.Errorf("EncodeGeohash = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeohashRoundTrip(t *testing.T) {
+	points := []Point{
+		{Lat: 57.64911, Lon: 10.40744},
+		{Lat: -33.8688, Lon: 151.2093},
+		// Cell edges belong to the cell north and east of them
+		{Lat: 0, Lon: 0},
+		{Lat: 45, Lon: 90},
+		{Lat: -90, Lon: -180},
+		{Lat: 90, Lon: 180},
+		{Lat: 0, Lon: -180},
+	}
+	for _, p := range points {
+		for precision := 1; precision <= 9; precision++ {
+			hash := EncodeGeohash(p, precision)
+			sw, ne := geohashBounds(hash)
+			inLat := p.Lat >= sw.Lat && (p.Lat < ne.Lat || ne.Lat == 90)
+			inLon := p.Lon >= sw.Lon && (p.Lon < ne.Lon || ne.Lon == 180)
+			if !inLat || !inLon {
+				t.Errorf("%v at precision %d: cell %s spans %v to %v", p, precision, hash, sw, ne)
+			}
+			height, width := GeohashCellSize(precision)
+			if h, w := ne.Lat-sw.Lat, ne.Lon-sw.Lon; h != height || w != width {
+				t.Errorf("cell %s is %g by %g degrees, GeohashCellSize says %g by %g", hash, h, w, height, width)
+			}
+		}
+	}
+}
+
+func TestGeohashesWithin(t *testing.T) {
+	center := Point{Lat: 0, Lon: 179.99}
+	hashes, ok := GeohashesWithin(center, 5000, 5, 100)
+	if !ok {
+		t.Fatal("GeohashesWithin reported too many cells")
+	}
+	cells := make(map[string]bool)
+	for _, h := range hashes {
+		cells[h] = true
+	}
+	// The circle reaches across the antimeridian and the equator
+	for _, p := range []Point{center, {Lat: 0.02, Lon: -179.99}, {Lat: -0.02, Lon: 179.96}} {
+		if h := EncodeGeohash(p, 5); !cells[h] {
+			t.Errorf("cell %s of %v missing from %v", h, p, hashes)
+		}
+	}
+	if _, ok := GeohashesWithin(Point{Lat: 89.9, Lon: 0}, 50000, 5, 100); ok {
+		t.Error("GeohashesWithin around the pole did not report too many cells")
+	}
+}
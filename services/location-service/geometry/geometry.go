@@ -0,0 +1,88 @@
+// Package geometry holds the spherical and planar calculations shared by
+// geofencing and track processing.
+package geometry
+
+import (
+	"math"
+	"strings"
+)
+
+// EarthRadiusMeters is the mean Earth radius used by Haversine
+const EarthRadiusMeters = 6371008.8
+
+// Point is a WGS 84 position in degrees
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Haversine returns the great-circle distance between a and b in meters
+func Haversine(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// InPolygon reports whether p lies inside the ring, using the even-odd
+// rule on longitude and latitude as planar coordinates. That is accurate
+// for site-sized polygons not crossing the antimeridian. The ring may be
+// open or closed.
+func InPolygon(p Point, ring []Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// MetersToDegreesLat converts a north-south distance to degrees of latitude
+func MetersToDegreesLat(m float64) float64 {
+	return m / EarthRadiusMeters * 180 / math.Pi
+}
+
+// MetersToDegreesLon converts an east-west distance at latitude lat to
+// degrees of longitude
+func MetersToDegreesLon(m, lat float64) float64 {
+	cos := math.Cos(radians(lat))
+	if cos < 1e-9 {
+		return 360
+	}
+	return MetersToDegreesLat(m) / cos
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// EncodePolyline encodes points in the Encoded Polyline Algorithm Format
+// with a precision of five decimals, as read by most map libraries
+func EncodePolyline(points []Point) string {
+	var b strings.Builder
+	var prevLat, prevLon int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * 1e5))
+		lon := int64(math.Round(p.Lon * 1e5))
+		encodePolylineValue(&b, lat-prevLat)
+		encodePolylineValue(&b, lon-prevLon)
+		prevLat, prevLon = lat, lon
+	}
+	return b.String()
+}
+
+func encodePolylineValue(b *strings.Builder, v int64) {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b.WriteByte(byte(0x20|u&0x1f) + 63)
+		u >>= 5
+	}
+	b.WriteByte(byte(u) + 63)
+}
@@ -0,0 +1,34 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		want float64 // meters
+	}{
+		{"same point", Point{Lat: 48.8566, Lon: 2.3522}, Point{Lat: 48.8566, Lon: 2.3522}, 0},
+		{"Paris to London", Point{Lat: 48.8566, Lon: 2.3522}, Point{Lat: 51.5074, Lon: -0.1278}, 343_560},
+		{"New York to Los Angeles", Point{Lat: 40.7128, Lon: -74.0060}, Point{Lat: 34.0522, Lon: -118.2437}, 3_935_750},
+		{"Sydney to Auckland", Point{Lat: -33.8688, Lon: 151.2093}, Point{Lat: -36.8485, Lon: 174.7633}, 2_155_900},
+		// One degree of the equator across the antimeridian, not 359
+		{"across the antimeridian", Point{Lat: 0, Lon: 179.5}, Point{Lat: 0, Lon: -179.5}, 111_195},
+		{"pole to pole", Point{Lat: 90, Lon: 0}, Point{Lat: -90, Lon: 0}, math.Pi * EarthRadiusMeters},
+		{"around the pole", Point{Lat: 89, Lon: 0}, Point{Lat: 89, Lon: 180}, 222_390},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Haversine(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 0.001*tt.want+0.001 {
+				t.Errorf("Haversine = %.0f m, want %.0f m", got, tt.want)
+			}
+			if back := Haversine(tt.b, tt.a); math.Abs(back-got) > 1e-6 {
+				t.Errorf("Haversine is not symmetric: %f and %f", got, back)
+			}
+		})
+	}
+}
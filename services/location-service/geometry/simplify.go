@@ -0,0 +1,115 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// PathLength returns the length of the line through points in meters
+func PathLength(points []Point) float64 {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += Haversine(points[i-1], points[i])
+	}
+	return total
+}
+
+// DistanceToSegment returns the distance in meters from p to the segment
+// between a and b. The points are projected onto a plane tangent at p,
+// which is accurate for segments up to a few hundred kilometers.
+func DistanceToSegment(p, a, b Point) float64 {
+	cos := math.Cos(radians(p.Lat))
+	project := func(q Point) (float64, float64) {
+		dLon := q.Lon - p.Lon
+		// Take the short way around the antimeridian
+		if dLon > 180 {
+			dLon -= 360
+		} else if dLon < -180 {
+			dLon += 360
+		}
+		return radians(dLon) * cos * EarthRadiusMeters, radians(q.Lat-p.Lat) * EarthRadiusMeters
+	}
+	ax, ay := project(a)
+	bx, by := project(b)
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if lenSq := dx*dx + dy*dy; lenSq > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lenSq))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// Simplify reduces a line with the Douglas-Peucker algorithm, dropping
+// points closer than toleranceMeters to the simplified line. It returns the
+// indices of the kept points in order; the first and last point are always
+// kept.
+func Simplify(points []Point, toleranceMeters float64) []int {
+	n := len(points)
+	if n <= 2 || toleranceMeters <= 0 {
+		return sequence(n)
+	}
+	keep := make([]bool, n)
+	keep[0], keep[n-1] = true, true
+
+	// Iterative, so long tracks cannot exhaust the stack
+	type span struct{ first, last int }
+	stack := []span{{0, n - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		maxDist, index := 0.0, -1
+		for i := s.first + 1; i < s.last; i++ {
+			if d := DistanceToSegment(points[i], points[s.first], points[s.last]); d > maxDist {
+				maxDist, index = d, i
+			}
+		}
+		if index < 0 || maxDist <= toleranceMeters {
+			continue
+		}
+		keep[index] = true
+		stack = append(stack, span{s.first, index}, span{index, s.last})
+	}
+
+	kept := make([]int, 0, n)
+	for i, k := range keep {
+		if k {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+// Downsample keeps the earliest point of every time bucket of the given
+// width, with buckets aligned to the Unix epoch. times may be in ascending
+// or descending order. It returns the indices of the kept points in input
+// order.
+func Downsample(times []time.Time, bucket time.Duration) []int {
+	if bucket <= 0 {
+		return sequence(len(times))
+	}
+	earliest := make(map[int64]int)
+	for i, t := range times {
+		key := t.UnixNano() / int64(bucket)
+		if t.UnixNano() < 0 && t.UnixNano()%int64(bucket) != 0 {
+			key--
+		}
+		if j, ok := earliest[key]; !ok || t.Before(times[j]) {
+			earliest[key] = i
+		}
+	}
+	kept := make([]int, 0, len(earliest))
+	for _, i := range earliest {
+		kept = append(kept, i)
+	}
+	sort.Ints(kept)
+	return kept
+}
+
+func sequence(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
@@ -0,0 +1,75 @@
+package geometry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// track returns points along a meridian, step degrees of latitude apart
+// (about 111 m per 0.001), shifted east by the given longitude offsets
+func track(step float64, lonOffsets ...float64) []Point {
+	points := make([]Point, len(lonOffsets))
+	for i, lon := range lonOffsets {
+		points[i] = Point{Lat: 45 + float64(i)*step, Lon: 7 + lon}
+	}
+	return points
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    []Point
+		tolerance float64
+		want      []int
+	}{
+		{"no points", nil, 10, []int{}},
+		{"one point", track(0.001, 0), 10, []int{0}},
+		{"two points", track(0.001, 0, 0), 10, []int{0, 1}},
+		{"straight line collapses to its endpoints", track(0.001, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0), 1, []int{0, 10}},
+		// Every other zigzag point lies about 79 m east of the line
+		{"zigzag above tolerance is kept", track(0.001, 0, 0.001, 0, 0.001, 0, 0.001, 0), 10, []int{0, 1, 2, 3, 4, 5, 6}},
+		{"zigzag below tolerance collapses", track(0.001, 0, 0.001, 0, 0.001, 0, 0.001, 0), 100, []int{0, 6}},
+		// A single detour of about 79 m in an otherwise straight line
+		{"detour above tolerance is kept", track(0.001, 0, 0, 0, 0.001, 0, 0, 0), 10, []int{0, 2, 3, 4, 6}},
+		{"zero tolerance keeps every point", track(0.001, 0, 0, 0, 0), 0, []int{0, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Simplify(tt.points, tt.tolerance); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Simplify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	at := func(seconds ...int) []time.Time {
+		times := make([]time.Time, len(seconds))
+		for i, s := range seconds {
+			times[i] = time.Unix(int64(s), 0)
+		}
+		return times
+	}
+	tests := []struct {
+		name   string
+		times  []time.Time
+		bucket time.Duration
+		want   []int
+	}{
+		{"no points", nil, time.Minute, []int{}},
+		{"one point", at(10), time.Minute, []int{0}},
+		{"two points in one bucket", at(10, 20), time.Minute, []int{0}},
+		{"ascending", at(0, 20, 40, 60, 80, 130), time.Minute, []int{0, 3, 5}},
+		{"descending keeps the earliest of each bucket", at(130, 80, 60, 40, 20, 0), time.Minute, []int{0, 2, 5}},
+		{"before the epoch", at(-90, -30, 0), time.Minute, []int{0, 1, 2}},
+		{"zero bucket keeps every point", at(0, 1, 2), 0, []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Downsample(tt.times, tt.bucket); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Downsample = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package geometry
+
+import "math"
+
+// MaxMercatorLat is the latitude where Web Mercator tiles end
+const MaxMercatorLat = 85.05112878
+
+// MaxTileZoom is the deepest supported tile zoom level
+const MaxTileZoom = 24
+
+// Tile is a Web Mercator map tile in the z/x/y scheme, with y growing
+// southwards
+type Tile struct {
+	Z int
+	X int
+	Y int
+}
+
+// Valid reports whether the tile exists at its zoom level
+func (t Tile) Valid() bool {
+	if t.Z < 0 || t.Z > MaxTileZoom {
+		return false
+	}
+	n := 1 << t.Z
+	return t.X >= 0 && t.X < n && t.Y >= 0 && t.Y < n
+}
+
+// TileAt returns the tile of zoom z containing p. Latitudes beyond the
+// Mercator range fall into the top or bottom row.
+func TileAt(p Point, z int) Tile {
+	lat := math.Max(-MaxMercatorLat, math.Min(MaxMercatorLat, p.Lat))
+	n := math.Exp2(float64(z))
+	x := math.Floor((p.Lon + 180) / 360 * n)
+	latRad := radians(lat)
+	y := math.Floor((1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n)
+	clamp := func(v float64) int { return int(math.Max(0, math.Min(n-1, v))) }
+	return Tile{Z: z, X: clamp(x), Y: clamp(y)}
+}
+
+// Center returns the position at the middle of the tile
+func (t Tile) Center() Point {
+	n := math.Exp2(float64(t.Z))
+	lon := (float64(t.X)+0.5)/n*360 - 180
+	lat := math.Atan(math.Sinh(math.Pi*(1-2*(float64(t.Y)+0.5)/n))) * 180 / math.Pi
+	return Point{Lat: lat, Lon: lon}
+}
@@ -0,0 +1,56 @@
+package geometry
+
+import "testing"
+
+func TestTileAt(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Point
+		z    int
+		want Tile
+	}{
+		{"whole world", Point{Lat: 51.5074, Lon: -0.1278}, 0, Tile{Z: 0, X: 0, Y: 0}},
+		{"origin", Point{Lat: 0, Lon: 0}, 1, Tile{Z: 1, X: 1, Y: 1}},
+		{"London", Point{Lat: 51.5074, Lon: -0.1278}, 10, Tile{Z: 10, X: 511, Y: 340}},
+		{"Paris", Point{Lat: 48.8566, Lon: 2.3522}, 12, Tile{Z: 12, X: 2074, Y: 1409}},
+		{"Sydney", Point{Lat: -33.8688, Lon: 151.2093}, 8, Tile{Z: 8, X: 235, Y: 153}},
+		{"antimeridian east edge", Point{Lat: 0, Lon: 180}, 3, Tile{Z: 3, X: 7, Y: 4}},
+		{"antimeridian west edge", Point{Lat: 0, Lon: -180}, 3, Tile{Z: 3, X: 0, Y: 4}},
+		{"north pole", Point{Lat: 90, Lon: 0}, 3, Tile{Z: 3, X: 4, Y: 0}},
+		{"south pole", Point{Lat: -90, Lon: 0}, 3, Tile{Z: 3, X: 4, Y: 7}},
+		{"Mercator limit", Point{Lat: MaxMercatorLat, Lon: -180}, MaxTileZoom, Tile{Z: MaxTileZoom, X: 0, Y: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TileAt(tt.p, tt.z)
+			if got != tt.want {
+				t.Errorf("TileAt = %d/%d/%d, want %d/%d/%d", got.Z, got.X, got.Y, tt.want.Z, tt.want.X, tt.want.Y)
+			}
+			if !got.Valid() {
+				t.Errorf("tile %v is not valid", got)
+			}
+			if back := TileAt(got.Center(), got.Z); back != got {
+				t.Errorf("center of %v lies in %v", got, back)
+			}
+		})
+	}
+}
+
+func TestTileValid(t *testing.T) {
+	tests := []struct {
+		t    Tile
+		want bool
+	}{
+		{Tile{Z: 0, X: 0, Y: 0}, true},
+		{Tile{Z: 3, X: 7, Y: 7}, true},
+		{Tile{Z: 3, X: 8, Y: 0}, false},
+		{Tile{Z: 3, X: 0, Y: -1}, false},
+		{Tile{Z: -1, X: 0, Y: 0}, false},
+		{Tile{Z: MaxTileZoom + 1, X: 0, Y: 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.t.Valid(); got != tt.want {
+			t.Errorf("%v.Valid() = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
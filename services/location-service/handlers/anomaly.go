@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/privacy"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/anomaly"
+	"github.com/himanshum9/go-mithril/services/location-service/cache"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+	"github.com/himanshum9/go-mithril/services/location-service/streaming"
+)
+
+var (
+	anomalyConfig anomaly.Config
+
+	// Publishes location.anomaly alerts
+	anomalyStreamer *streaming.Streamer
+
+	// Last stored point of each user as received, before the privacy policy
+	// rounded it; the baseline of anomaly scoring
+	scoredLocations = cache.NewLatestLocations()
+)
+
+// GetAnomalySettings returns the anomaly handling of the caller's tenant
+func GetAnomalySettings(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	s, err := tenantAnomalySettings(r.Context(), p.TenantID)
+	if err != nil {
+		http.Error(w, "Failed to load anomaly settings", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(s)
+}
+
+// UpdateAnomalySettings chooses whether anomalous points of the caller's
+// tenant are only flagged or rejected: PUT /settings/anomaly
+func UpdateAnomalySettings(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	s := models.AnomalySettings{RejectScore: appConfig.Anomaly.RejectScore}
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	v := validation.New()
+	s.Validate(v)
+	if fields := v.Errors(); len(fields) > 0 {
+		apierror.WriteValidation(w, fields)
+		return
+	}
+	s.TenantID = p.TenantID
+	if err := models.SaveAnomalySettings(r.Context(), &s); err != nil {
+		http.Error(w, "Failed to save anomaly settings", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(s)
+}
+
+func settingsPrincipal(w http.ResponseWriter, r *http.Request) (principal, bool) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return p, false
+	}
+	if p.Role != "admin" {
+		http.Error(w, "Forbidden: Tenant admins only", http.StatusForbidden)
+		return p, false
+	}
+	return p, true
+}
+
+// tenantAnomalySettings returns the settings of a tenant, defaulting to
+// flag-only
+func tenantAnomalySettings(ctx context.Context, tenantID string) (*models.AnomalySettings, error) {
+	s, err := models.GetAnomalySettings(ctx, tenantID)
+	if errors.Is(err, models.ErrAnomalySettingsNotFound) {
+		return &models.AnomalySettings{TenantID: tenantID, Mode: models.AnomalyModeFlag, RejectScore: appConfig.Anomaly.RejectScore}, nil
+	}
+	return s, err
+}
+
+// anomalyScorer scores the new points of one tenant user. Points must be
+// passed in timestamp order; each is compared with the last accepted one,
+// starting from the user's last stored point as received. A stored point
+// the privacy policy rounded is no baseline, as its distances are off.
+type anomalyScorer struct {
+	settings *models.AnomalySettings
+	prev     *models.Location
+	now      time.Time
+
+	// Accepted points as received, until they are stored
+	pending map[*models.Location]models.Location
+}
+
+func newAnomalyScorer(ctx context.Context, tenantID, userID string, policy *privacy.Policy) (*anomalyScorer, error) {
+	settings, err := tenantAnomalySettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	s := &anomalyScorer{settings: settings, now: time.Now(), pending: make(map[*models.Location]models.Location)}
+	stored, hasStored := latestLocations.Get(tenantID, userID)
+	raw, hasRaw := scoredLocations.Get(tenantID, userID)
+	switch {
+	case hasRaw && (!hasStored || !stored.Timestamp.After(raw.Timestamp)):
+		s.prev = &raw
+	case hasStored && !policy.Rounds(stored.Timestamp):
+		s.prev = &stored
+	}
+	return s, nil
+}
+
+// score stores the risk of l on it and reports whether the tenant rejects
+// it. Rejected points raise their alert at once since they are never
+// stored.
+func (s *anomalyScorer) score(ctx context.Context, l *models.Location) bool {
+	result := anomaly.Check(anomalyConfig, s.prev, l, s.now)
+	result.Apply(l)
+	if s.settings.Rejects(result.Score) {
+		if result.Score >= anomalyConfig.AlertScore {
+			publishAnomalyAlerts(ctx, []models.AnomalyAlert{models.NewAnomalyAlert(l, models.AnomalyActionRejected)})
+		}
+		return true
+	}
+	return false
+}
+
+// accept makes l the baseline of the next points of the request once it
+// passed every check. It must be called before the privacy policy applies
+// to l.
+func (s *anomalyScorer) accept(l *models.Location) {
+	raw := *l
+	s.pending[l] = raw
+	if s.prev == nil || raw.Timestamp.After(s.prev.Timestamp) {
+		s.prev = &raw
+	}
+}
+
+// stored makes the accepted points among locations that were stored the
+// baseline of later requests
+func (s *anomalyScorer) stored(locations []*models.Location) {
+	for _, l := range locations {
+		if raw, ok := s.pending[l]; ok && l.ID != 0 {
+			raw.ID = l.ID
+			scoredLocations.Update(raw)
+		}
+	}
+}
+
+// anomalyRejection describes why l was rejected
+func anomalyRejection(l *models.Location) string {
+	return "Location rejected as anomalous: " + strings.Join(l.Anomalies, ", ")
+}
+
+// alertStoredAnomalies publishes alerts for stored points whose risk
+// reaches the alert score
+func alertStoredAnomalies(ctx context.Context, locations []*models.Location) {
+	var alerts []models.AnomalyAlert
+	for _, l := range locations {
+		if l.RiskScore != nil && *l.RiskScore > 0 && *l.RiskScore >= anomalyConfig.AlertScore {
+			alerts = append(alerts, models.NewAnomalyAlert(l, models.AnomalyActionFlagged))
+		}
+	}
+	publishAnomalyAlerts(ctx, alerts)
+}
+
+func publishAnomalyAlerts(ctx context.Context, alerts []models.AnomalyAlert) {
+	if len(alerts) == 0 {
+		return
+	}
+	if err := anomalyStreamer.StreamAnomalyAlerts(ctx, alerts); err != nil {
+		log.Printf("anomaly alerts for tenant %s failed: %v", alerts[0].TenantID, err)
+	}
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+const (
+	BatchItemCreated   = "created"
+	BatchItemRejected  = "rejected"
+	BatchItemDiscarded = "discarded" // Outside working hours under a drop policy
+)
+
+// LocationBatchRequest represents the payload for buffered location uploads
+type LocationBatchRequest struct {
+	Points []LocationSubmissionRequest `json:"points"`
+}
+
+// BatchItemResult reports the outcome of a single point of a batch, in the
+// order the points were sent
+type BatchItemResult struct {
+	Index         int    `json:"index"`
+	Status        string `json:"status"`
+	LocationID    int64  `json:"location_id,omitempty"`
+	ClientPointID string `json:"client_point_id,omitempty"`
+	Replayed      bool   `json:"replayed,omitempty"`
+	Code          int    `json:"code,omitempty"`
+	Error         string `json:"error,omitempty"`
+
+	Fields []apierror.FieldError `json:"fields,omitempty"`
+}
+
+// SubmitLocationBatch accepts points buffered by a device while offline.
+// Each point is checked on its own; points may arrive after their session
+// has ended as long as their timestamp falls inside it. Accepted points are
+// stored with one statement and streamed with one Kafka write.
+func SubmitLocationBatch(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if p.Role != "user" {
+		http.Error(w, "Forbidden: Tenant users only", http.StatusForbidden)
+		return
+	}
+
+	var req LocationBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	if len(req.Points) == 0 {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeValidationFailed, "No points submitted",
+			apierror.FieldError{Field: "points", Code: apierror.FieldRequired, Message: "points must not be empty"})
+		return
+	}
+	if max := min(appConfig.Ingest.BatchMaxPoints, models.MaxSaveLocations); len(req.Points) > max {
+		http.Error(w, fmt.Sprintf("Batch exceeds %d points", max), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Points without their own client_point_id derive one from the batch
+	// Idempotency-Key, so a retried upload is recognised point by point
+	batchKey := r.Header.Get(IdempotencyKeyHeader)
+	clientPointIDs := make([]string, len(req.Points))
+	for i, pt := range req.Points {
+		clientPointIDs[i] = pt.ClientPointID
+		if clientPointIDs[i] == "" && batchKey != "" {
+			clientPointIDs[i] = fmt.Sprintf("%s:%d", batchKey, i)
+		}
+	}
+	existing, err := models.GetLocationsByClientPointIDs(r.Context(), p.TenantID, p.UserID, nonEmpty(clientPointIDs))
+	if err != nil {
+		http.Error(w, "Failed to check for duplicate locations", http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]BatchItemResult, len(req.Points))
+	reject := func(i, code int, msg string) {
+		results[i] = BatchItemResult{Index: i, Status: BatchItemRejected, ClientPointID: clientPointIDs[i], Code: code, Error: msg}
+	}
+	replay := func(i int, l models.Location) {
+		results[i] = BatchItemResult{Index: i, Status: BatchItemCreated, LocationID: l.ID, ClientPointID: l.ClientPointID, Replayed: true}
+	}
+
+	// Buffered points may be of any age; their session bounds them instead
+	now := time.Now()
+	var order []int
+	for i := range req.Points {
+		if err := req.Points[i].validate(now, 0); err != nil {
+			var fields validation.Errors
+			if !errors.As(err, &fields) {
+				reject(i, http.StatusBadRequest, err.Error())
+				continue
+			}
+			results[i] = BatchItemResult{Index: i, Status: BatchItemRejected, ClientPointID: clientPointIDs[i],
+				Code: http.StatusBadRequest, Error: "Validation failed", Fields: fields}
+			continue
+		}
+		if len(clientPointIDs[i]) > maxClientPointIDLength {
+			reject(i, http.StatusBadRequest, fmt.Sprintf("client_point_id exceeds %d characters", maxClientPointIDLength))
+			continue
+		}
+		order = append(order, i)
+	}
+
+	// The interval limiter only accepts increasing timestamps per session
+	sort.SliceStable(order, func(a, b int) bool {
+		return *req.Points[order[a]].Timestamp < *req.Points[order[b]].Timestamp
+	})
+
+	policy, err := models.EffectivePrivacyPolicy(r.Context(), p.TenantID, p.UserID)
+	if err != nil {
+		http.Error(w, "Failed to load privacy policy", http.StatusInternalServerError)
+		return
+	}
+	scorer, err := newAnomalyScorer(r.Context(), p.TenantID, p.UserID, policy)
+	if err != nil {
+		http.Error(w, "Failed to load anomaly settings", http.StatusInternalServerError)
+		return
+	}
+	sessions := make(map[string]*models.Session)
+	seen := make(map[string]bool)
+	var accepted []*models.Location
+	var acceptedIdx []int
+	for _, i := range order {
+		pt := req.Points[i]
+
+		if id := clientPointIDs[i]; id != "" {
+			if l, ok := existing[id]; ok {
+				replay(i, l)
+				continue
+			}
+			if seen[id] {
+				reject(i, http.StatusConflict, "Duplicate client_point_id in batch")
+				continue
+			}
+			seen[id] = true
+		}
+
+		session, cached := sessions[pt.SessionID]
+		if !cached {
+			var err error
+			session, err = models.GetSession(r.Context(), p.TenantID, pt.SessionID)
+			if err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+				http.Error(w, "Failed to load session", http.StatusInternalServerError)
+				return
+			}
+			if session != nil && session.UserID != p.UserID {
+				session = nil
+			}
+			sessions[pt.SessionID] = session
+		}
+		if session == nil {
+			reject(i, http.StatusNotFound, "Session not found")
+			continue
+		}
+
+		timestamp := pt.Time()
+		if !session.Covers(timestamp) {
+			reject(i, http.StatusBadRequest, "Timestamp outside of session")
+			continue
+		}
+		if policy.Drops(timestamp) {
+			results[i] = BatchItemResult{Index: i, Status: BatchItemDiscarded, ClientPointID: clientPointIDs[i]}
+			continue
+		}
+
+		location := &models.Location{
+			TenantID:      p.TenantID,
+			UserID:        p.UserID,
+			Latitude:      *pt.Latitude,
+			Longitude:     *pt.Longitude,
+			Timestamp:     timestamp,
+			SessionID:     session.ID,
+			ClientPointID: clientPointIDs[i],
+			Telemetry:     pt.Telemetry,
+		}
+		if scorer.score(r.Context(), location) {
+			reject(i, http.StatusUnprocessableEntity, anomalyRejection(location))
+			continue
+		}
+
+		allowed, err := submissionLimiter.Allow(r.Context(), session.ID, timestamp)
+		if err != nil {
+			http.Error(w, "Failed to check submission interval", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			reject(i, http.StatusTooManyRequests, "Submission interval too short")
+			continue
+		}
+		// The batch is stored as a whole, so later points of it compare
+		// with this one
+		scorer.accept(location)
+
+		location.Latitude, location.Longitude = policy.Apply(location.Latitude, location.Longitude, timestamp)
+		accepted = append(accepted, location)
+		acceptedIdx = append(acceptedIdx, i)
+	}
+
+	var inserted []*models.Location
+	if len(accepted) > 0 {
+		if err := models.SaveLocations(r.Context(), accepted); err != nil {
+			releaseSubmissions(r.Context(), accepted)
+			http.Error(w, "Failed to save locations", http.StatusInternalServerError)
+			return
+		}
+
+		// Points left without an ID were stored by a concurrent retry
+		var raced []string
+		for _, l := range accepted {
+			if l.ID == 0 {
+				raced = append(raced, l.ClientPointID)
+			} else {
+				inserted = append(inserted, l)
+			}
+		}
+		if len(raced) > 0 {
+			if existing, err = models.GetLocationsByClientPointIDs(r.Context(), p.TenantID, p.UserID, raced); err != nil {
+				http.Error(w, "Failed to check for duplicate locations", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		scorer.stored(inserted)
+		processIngested(r.Context(), p.TenantID, p.UserID, inserted)
+	}
+	for n, i := range acceptedIdx {
+		l := accepted[n]
+		if l.ID == 0 {
+			replay(i, existing[l.ClientPointID])
+			continue
+		}
+		results[i] = BatchItemResult{Index: i, Status: BatchItemCreated, LocationID: l.ID, ClientPointID: l.ClientPointID}
+	}
+
+	created, discarded := 0, 0
+	for _, res := range results {
+		switch res.Status {
+		case BatchItemCreated:
+			created++
+		case BatchItemDiscarded:
+			discarded++
+		}
+	}
+	// Discarded points were handled as the tenant asked; only rejections
+	// make the upload partial
+	handled := created + discarded
+	status := http.StatusCreated
+	switch {
+	case handled == 0:
+		status = http.StatusUnprocessableEntity
+	case handled < len(req.Points):
+		status = http.StatusMultiStatus
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"accepted":  created,
+		"discarded": discarded,
+		"rejected":  len(req.Points) - handled,
+		"results":   results,
+	})
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
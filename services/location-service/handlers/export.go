@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/services/location-service/export"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// ExportLocations streams the track of a user or session as GeoJSON, GPX,
+// KML or CSV: GET /locations/export?user_id=|session_id=&from=&to=&format=
+// &simplify_tolerance_m=&bucket=
+// The format query parameter takes precedence over the Accept header.
+func ExportLocations(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q, reduction, err := locationQueryFromRequest(r, p)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	values := r.URL.Query()
+	if values.Get("user_id") == "" && q.SessionID == "" && p.Role == "admin" {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, "user_id or session_id is required")
+		return
+	}
+
+	var format export.Format
+	names := strings.Join(export.Names(), ", ")
+	if name := values.Get("format"); name != "" {
+		if format, ok = export.Lookup(name); !ok {
+			apierror.WriteValidation(w, []apierror.FieldError{
+				{Field: "format", Code: apierror.FieldInvalid, Message: "format must be one of " + names},
+			})
+			return
+		}
+	} else if format, ok = export.Negotiate(r.Header.Get("Accept")); !ok {
+		apierror.Write(w, http.StatusNotAcceptable, apierror.CodeInvalidRequest,
+			"Unsupported export format, expected one of: "+names)
+		return
+	}
+
+	name := "track"
+	if q.SessionID != "" {
+		name = "session-" + q.SessionID
+	} else if q.UserID != "" {
+		name = "user-" + q.UserID
+	}
+
+	src := func(fn func(models.Location) error) error {
+		return models.EachLocation(r.Context(), q, fn)
+	}
+	if reduction.enabled() {
+		// Simplification needs the whole track before anything is written
+		locations, err := loadReduced(r.Context(), q, reduction)
+		if errors.Is(err, errTooManyPoints) {
+			writeQueryError(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to export locations", http.StatusInternalServerError)
+			return
+		}
+		src = func(fn func(models.Location) error) error {
+			for _, l := range locations {
+				if err := fn(l); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+safeFilename(name)+"."+format.Extension+`"`)
+	if err := format.Write(w, name, src); err != nil {
+		// Headers are already sent; the client sees a truncated file
+		log.Printf("location export for tenant %s failed: %v", p.TenantID, err)
+	}
+}
+
+func safeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, s)
+}
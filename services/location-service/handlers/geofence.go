@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/featureflags"
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// geofencingPrincipal authenticates the caller and checks that geofencing
+// is enabled for their tenant. Writes additionally require a tenant admin.
+func geofencingPrincipal(w http.ResponseWriter, r *http.Request, write bool) (principal, bool) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return p, false
+	}
+	if !featureFlags.Enabled(r.Context(), p.TenantID, featureflags.Geofencing) {
+		http.Error(w, "Geofencing is not enabled for this tenant", http.StatusForbidden)
+		return p, false
+	}
+	if write && p.Role != "admin" {
+		http.Error(w, "Forbidden: Tenant admins only", http.StatusForbidden)
+		return p, false
+	}
+	return p, true
+}
+
+// CreateGeofence adds a circle or polygon geofence to the caller's tenant
+func CreateGeofence(w http.ResponseWriter, r *http.Request) {
+	p, ok := geofencingPrincipal(w, r, true)
+	if !ok {
+		return
+	}
+	g, ok := decodeGeofence(w, r)
+	if !ok {
+		return
+	}
+	g.TenantID = p.TenantID
+	if err := models.SaveGeofence(r.Context(), g); err != nil {
+		http.Error(w, "Failed to create geofence", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(g)
+}
+
+// ListGeofences returns the geofences of the caller's tenant ordered by name:
+// GET /geofences?limit=&cursor=&order=
+func ListGeofences(w http.ResponseWriter, r *http.Request) {
+	p, ok := geofencingPrincipal(w, r, false)
+	if !ok {
+		return
+	}
+	params, err := pagination.ParseQuery(r.URL.Query(), "name")
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	page, err := models.ListGeofences(r.Context(), p.TenantID, params)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		writeQueryError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to list geofences", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+func GetGeofence(w http.ResponseWriter, r *http.Request) {
+	p, ok := geofencingPrincipal(w, r, false)
+	if !ok {
+		return
+	}
+	g, err := models.GetGeofence(r.Context(), p.TenantID, r.PathValue("id"))
+	if err != nil {
+		writeGeofenceError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(g)
+}
+
+// UpdateGeofence replaces the definition of a geofence
+func UpdateGeofence(w http.ResponseWriter, r *http.Request) {
+	p, ok := geofencingPrincipal(w, r, true)
+	if !ok {
+		return
+	}
+	g, ok := decodeGeofence(w, r)
+	if !ok {
+		return
+	}
+	g.ID = r.PathValue("id")
+	g.TenantID = p.TenantID
+	if err := models.UpdateGeofence(r.Context(), g); err != nil {
+		writeGeofenceError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(g)
+}
+
+func DeleteGeofence(w http.ResponseWriter, r *http.Request) {
+	p, ok := geofencingPrincipal(w, r, true)
+	if !ok {
+		return
+	}
+	if err := models.DeleteGeofence(r.Context(), p.TenantID, r.PathValue("id")); err != nil {
+		writeGeofenceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListGeofenceEvents returns the geofence event history of the caller's
+// tenant: GET /geofences/events?user_id=&geofence_id=&type=&from=&to=&limit=&cursor=&order=
+// Tenant users only see their own events.
+func ListGeofenceEvents(w http.ResponseWriter, r *http.Request) {
+	p, ok := geofencingPrincipal(w, r, false)
+	if !ok {
+		return
+	}
+	values := r.URL.Query()
+	params, err := pagination.ParseQuery(values, "occurred_at")
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	q := models.GeofenceEventQuery{
+		Params:     params,
+		TenantID:   p.TenantID,
+		UserID:     values.Get("user_id"),
+		GeofenceID: values.Get("geofence_id"),
+		Type:       values.Get("type"),
+	}
+	if p.Role != "admin" {
+		if q.UserID != "" && q.UserID != p.UserID {
+			writeQueryError(w, errForbiddenUser)
+			return
+		}
+		q.UserID = p.UserID
+	}
+	v := validation.New()
+	if q.Type != "" {
+		v.OneOf("type", q.Type, models.GeofenceEventEnter, models.GeofenceEventExit, models.GeofenceEventDwell)
+	}
+	q.From = parseTimeParam(v, values, "from")
+	q.To = parseTimeParam(v, values, "to")
+	if err := v.Err(); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	page, err := models.QueryGeofenceEvents(r.Context(), q)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		writeQueryError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to list geofence events", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+func decodeGeofence(w http.ResponseWriter, r *http.Request) (*models.Geofence, bool) {
+	var g models.Geofence
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return nil, false
+	}
+	v := validation.New()
+	g.Validate(v)
+	if fields := v.Errors(); len(fields) > 0 {
+		apierror.WriteValidation(w, fields)
+		return nil, false
+	}
+	// Keep only the shape of the chosen type
+	if g.Type == models.GeofenceTypeCircle {
+		g.Polygon = nil
+	} else {
+		g.Center, g.RadiusMeters = nil, 0
+	}
+	return &g, true
+}
+
+func writeGeofenceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrGeofenceNotFound) {
+		http.Error(w, "Geofence not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Failed to access geofence", http.StatusInternalServerError)
+}
+
+// evaluateGeofences records the geofence transitions of new points and
+// publishes the resulting events
+func evaluateGeofences(ctx context.Context, tenantID, userID string, locations []*models.Location) error {
+	events, err := models.EvaluateGeofences(ctx, tenantID, userID, locations)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	return geofenceStreamer.StreamGeofenceEvents(ctx, events)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+	"github.com/himanshum9/go-mithril/services/location-service/mvt"
+)
+
+const (
+	// heatmapGridShift splits a tile into 64 by 64 cells, fewer from zoom
+	// 13 on where they would be finer than the rollup
+	heatmapGridShift = 6
+
+	defaultHeatmapWindow = 7 * 24 * time.Hour
+	maxHeatmapWindow     = 366 * 24 * time.Hour
+)
+
+// HeatmapTile returns the point density of the caller's tenant in the map
+// tile z/x/y: GET /heatmap/{z}/{x}/{y}?from=&to=&format=json|mvt
+// Counts come from the hourly rollup, so the window is widened to whole
+// hours. Tenant admins only.
+func HeatmapTile(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if p.Role != "admin" {
+		http.Error(w, "Forbidden: Tenant admins only", http.StatusForbidden)
+		return
+	}
+
+	var tile geometry.Tile
+	var err error
+	tile.Z, err = strconv.Atoi(r.PathValue("z"))
+	if err == nil {
+		tile.X, err = strconv.Atoi(r.PathValue("x"))
+	}
+	if err == nil {
+		tile.Y, err = strconv.Atoi(r.PathValue("y"))
+	}
+	if err != nil || !tile.Valid() || tile.Z > models.HeatmapZoom {
+		http.Error(w, "Tile not found", http.StatusNotFound)
+		return
+	}
+
+	values := r.URL.Query()
+	v := validation.New()
+	from := parseTimeParam(v, values, "from")
+	to := parseTimeParam(v, values, "to")
+	if fields := v.Errors(); len(fields) > 0 {
+		apierror.WriteValidation(w, fields)
+		return
+	}
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-defaultHeatmapWindow)
+	if from != nil {
+		start = *from
+	}
+	start = start.Truncate(time.Hour)
+	if t := end.Truncate(time.Hour); t.Before(end) {
+		end = t.Add(time.Hour)
+	}
+	if !start.Before(end) {
+		v.Add("to", apierror.FieldInvalid, "to must be after from")
+	} else if end.Sub(start) > maxHeatmapWindow {
+		v.Add("from", apierror.FieldOutOfRange, "the window must not exceed 366 days")
+	}
+	if fields := v.Errors(); len(fields) > 0 {
+		apierror.WriteValidation(w, fields)
+		return
+	}
+
+	format := values.Get("format")
+	if format == "" {
+		format = "json"
+		if strings.Contains(r.Header.Get("Accept"), mvt.ContentType) {
+			format = "mvt"
+		}
+	}
+	if format != "json" && format != "mvt" {
+		apierror.Write(w, http.StatusNotAcceptable, apierror.CodeInvalidRequest, "Unsupported heatmap format, expected one of: json, mvt")
+		return
+	}
+
+	cellZoom := min(tile.Z+heatmapGridShift, models.HeatmapZoom)
+	cells, err := models.QueryHeatmap(r.Context(), p.TenantID, tile, cellZoom, start, end)
+	if err != nil {
+		http.Error(w, "Failed to load heatmap", http.StatusInternalServerError)
+		return
+	}
+
+	if format == "mvt" {
+		w.Header().Set("Content-Type", mvt.ContentType)
+		w.Write(heatmapMVT(tile, cellZoom, cells))
+		return
+	}
+	var total int64
+	for _, c := range cells {
+		total += c.Count
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"z":         tile.Z,
+		"x":         tile.X,
+		"y":         tile.Y,
+		"cell_zoom": cellZoom,
+		"from":      start,
+		"to":        end,
+		"total":     total,
+		"cells":     cells,
+	})
+}
+
+// heatmapMVT encodes the cells as points at their centers in a "heatmap"
+// layer, each with a count property
+func heatmapMVT(tile geometry.Tile, cellZoom int, cells []models.HeatmapCell) []byte {
+	span := cellZoom - tile.Z
+	size := int32(mvt.DefaultExtent >> span)
+	features := make([]mvt.Feature, len(cells))
+	for i, c := range cells {
+		col := int32(c.X - tile.X<<span)
+		row := int32(c.Y - tile.Y<<span)
+		features[i] = mvt.Feature{
+			X:          col*size + size/2,
+			Y:          row*size + size/2,
+			Properties: map[string]int64{"count": c.Count},
+		}
+	}
+	return mvt.Encode(mvt.Layer{Name: "heatmap", Extent: mvt.DefaultExtent, Features: features})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// ListLocations returns the location history of the caller's tenant:
+// GET /locations?user_id=&session_id=&from=&to=&bbox=&limit=&cursor=&order=
+// &simplify_tolerance_m=&bucket=
+// Tenant users only see their own points. Simplified history is returned
+// in one response without a cursor.
+func ListLocations(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q, reduction, err := locationQueryFromRequest(r, p)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	if reduction.enabled() {
+		locations, err := loadReduced(r.Context(), q, reduction)
+		if errors.Is(err, errTooManyPoints) {
+			writeQueryError(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to list locations", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(pagination.Page[models.Location]{Items: locations})
+		return
+	}
+	page, err := models.QueryLocations(r.Context(), q)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		writeQueryError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to list locations", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// locationQueryFromRequest reads the history filters and track
+// simplification shared by the history and export endpoints, scoped to the
+// caller's tenant
+func locationQueryFromRequest(r *http.Request, p principal) (models.LocationQuery, trackReduction, error) {
+	values := r.URL.Query()
+	params, err := pagination.ParseQuery(values, "timestamp")
+	if err != nil {
+		return models.LocationQuery{}, trackReduction{}, err
+	}
+	q := models.LocationQuery{
+		Params:    params,
+		TenantID:  p.TenantID,
+		UserID:    values.Get("user_id"),
+		SessionID: values.Get("session_id"),
+	}
+	if p.Role != "admin" {
+		if q.UserID != "" && q.UserID != p.UserID {
+			return q, trackReduction{}, errForbiddenUser
+		}
+		q.UserID = p.UserID
+	}
+
+	v := validation.New()
+	q.From = parseTimeParam(v, values, "from")
+	q.To = parseTimeParam(v, values, "to")
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		v.Add("to", apierror.FieldInvalid, "to must be after from")
+	}
+	q.BBox = parseBBoxParam(v, values, "bbox")
+	reduction := parseTrackReduction(v, values)
+	if reduction.enabled() && q.Cursor != nil {
+		v.Add("cursor", apierror.FieldInvalid, "cursor cannot be combined with simplify_tolerance_m or bucket")
+	}
+	return q, reduction, v.Err()
+}
+
+var errForbiddenUser = errors.New("Forbidden: Access to other users denied")
+
+func writeQueryError(w http.ResponseWriter, err error) {
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		apierror.WriteValidation(w, fields)
+	case errors.Is(err, errForbiddenUser):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+	}
+}
+
+// parseTimeParam accepts RFC 3339 or Unix seconds
+func parseTimeParam(v *validation.Validator, values url.Values, name string) *time.Time {
+	s := values.Get(name)
+	if s == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		t := time.Unix(secs, 0)
+		return &t
+	}
+	v.Add(name, apierror.FieldInvalid, name+" must be RFC 3339 or Unix seconds")
+	return nil
+}
+
+// parseBBoxParam reads min_lon,min_lat,max_lon,max_lat, the GeoJSON order
+func parseBBoxParam(v *validation.Validator, values url.Values, name string) *models.BoundingBox {
+	s := values.Get(name)
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		v.Add(name, apierror.FieldInvalid, name+" must be min_lon,min_lat,max_lon,max_lat")
+		return nil
+	}
+	var coords [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			v.Add(name, apierror.FieldInvalid, name+" must be min_lon,min_lat,max_lon,max_lat")
+			return nil
+		}
+		coords[i] = f
+	}
+	b := &models.BoundingBox{MinLon: coords[0], MinLat: coords[1], MaxLon: coords[2], MaxLat: coords[3]}
+	v.Longitude(name+".min_lon", &b.MinLon)
+	v.Latitude(name+".min_lat", &b.MinLat)
+	v.Longitude(name+".max_lon", &b.MaxLon)
+	v.Latitude(name+".max_lat", &b.MaxLat)
+	if b.MinLat > b.MaxLat {
+		v.Add(name, apierror.FieldInvalid, "min_lat must not exceed max_lat")
+	}
+	return b
+}
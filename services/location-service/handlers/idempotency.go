@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+const (
+	IdempotencyKeyHeader   = "Idempotency-Key"
+	IdempotentReplayHeader = "Idempotent-Replayed"
+	maxClientPointIDLength = 255
+)
+
+// clientPointIDFor returns the client point ID of a single submission, taken
+// from the Idempotency-Key header or the client_point_id field
+func clientPointIDFor(r *http.Request, bodyID string) (string, error) {
+	headerID := r.Header.Get(IdempotencyKeyHeader)
+	if headerID != "" && bodyID != "" && headerID != bodyID {
+		return "", fmt.Errorf("%s header and client_point_id differ", IdempotencyKeyHeader)
+	}
+	id := bodyID
+	if id == "" {
+		id = headerID
+	}
+	if len(id) > maxClientPointIDLength {
+		return "", fmt.Errorf("client_point_id exceeds %d characters", maxClientPointIDLength)
+	}
+	return id, nil
+}
+
+// findSubmitted returns the location already stored for a client point ID,
+// or nil if there is none
+func findSubmitted(ctx context.Context, p principal, clientPointID string) (*models.Location, error) {
+	existing, err := models.GetLocationsByClientPointIDs(ctx, p.TenantID, p.UserID, []string{clientPointID})
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := existing[clientPointID]; ok {
+		return &l, nil
+	}
+	return nil, nil
+}
+
+// writeReplay answers a retried submission with the originally stored location
+func writeReplay(w http.ResponseWriter, l *models.Location) {
+	w.Header().Set(IdempotentReplayHeader, "true")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Location submitted", "location": l})
+}
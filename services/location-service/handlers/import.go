@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/privacy"
+	"github.com/himanshum9/go-mithril/services/location-service/importer"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// ImportLocations starts an asynchronous import of a historical track file:
+// POST /locations/import?user_id=&format=&replay=
+// The file is the request body, or the "file" part of a multipart form. The
+// format is detected from the file name or Content-Type unless given.
+// Tenant users may only import their own history. Responds 202 with the
+// job, which is polled at GET /locations/import/{id}.
+func ImportLocations(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	values := r.URL.Query()
+	userID := values.Get("user_id")
+	if p.Role != "admin" {
+		if userID != "" && userID != p.UserID {
+			http.Error(w, errForbiddenUser.Error(), http.StatusForbidden)
+			return
+		}
+		userID = p.UserID
+	}
+	if userID == "" {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, "user_id is required")
+		return
+	}
+	replay := false
+	if s := values.Get("replay"); s != "" {
+		var err error
+		if replay, err = strconv.ParseBool(s); err != nil {
+			apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, "replay must be true or false")
+			return
+		}
+	}
+
+	data, filename, contentType, err := readImportFile(w, r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierror.Write(w, http.StatusRequestEntityTooLarge, apierror.CodeInvalidRequest, "Import file too large")
+			return
+		}
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var format importer.Format
+	if name := values.Get("format"); name != "" {
+		format, ok = importer.Lookup(name)
+	} else {
+		format, ok = importer.Detect(filename, contentType)
+	}
+	if !ok {
+		apierror.Write(w, http.StatusUnsupportedMediaType, apierror.CodeInvalidRequest,
+			"Unsupported import format, expected one of: "+strings.Join(importer.Names(), ", "))
+		return
+	}
+
+	policy, err := models.EffectivePrivacyPolicy(r.Context(), p.TenantID, userID)
+	if err != nil {
+		http.Error(w, "Failed to load privacy policy", http.StatusInternalServerError)
+		return
+	}
+
+	job := models.NewImportJob(p.TenantID, userID, format.Name, replay, int64(len(data)))
+	if err := models.SaveImportJob(r.Context(), job); err != nil {
+		http.Error(w, "Failed to create import job", http.StatusInternalServerError)
+		return
+	}
+	go runImport(job, format, data, policy)
+
+	w.Header().Set("Location", "/locations/import/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetImportJob returns the progress and line errors of an import job
+func GetImportJob(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	job, err := models.GetImportJob(r.Context(), p.TenantID, r.PathValue("id"))
+	if err == nil && p.Role != "admin" && job.UserID != p.UserID {
+		err = models.ErrImportJobNotFound
+	}
+	if errors.Is(err, models.ErrImportJobNotFound) {
+		http.Error(w, "Import job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get import job", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(job)
+}
+
+// readImportFile reads the uploaded file, bounded by the configured maximum
+// size. The whole file is held in memory since the job outlives the request.
+func readImportFile(w http.ResponseWriter, r *http.Request) ([]byte, string, string, error) {
+	body := http.MaxBytesReader(w, r.Body, appConfig.GetImportMaxBytes())
+	contentType := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "multipart/form-data") {
+		data, err := io.ReadAll(body)
+		if err == nil && len(data) == 0 {
+			err = errors.New("empty import file")
+		}
+		return data, "", contentType, err
+	}
+
+	r.Body = body
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err == nil && len(data) == 0 {
+		err = errors.New("empty import file")
+	}
+	return data, header.Filename, header.Header.Get("Content-Type"), err
+}
+
+func runImport(job *models.ImportJob, format importer.Format, data []byte, policy *privacy.Policy) {
+	err := importer.Run(context.Background(), job, format, bytes.NewReader(data), importer.Options{
+		MaxClockSkew: appConfig.GetMaxClockSkew(),
+		Privacy:      policy,
+		Stored: func(locations []*models.Location) {
+			for _, l := range locations {
+				latestLocations.Update(*l)
+			}
+		},
+	})
+	if err != nil {
+		log.Printf("import job %s for tenant %s failed: %v", job.ID, job.TenantID, err)
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/himanshum9/go-mithril/pkg/featureflags"
+	"github.com/himanshum9/go-mithril/services/location-service/activity"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+	"github.com/himanshum9/go-mithril/services/location-service/trips"
+)
+
+var (
+	// Segments the location streams of users into trips and stops
+	tripProcessor *trips.Processor
+
+	// Maintains the hourly and daily activity rollups of users
+	activityRecorder *activity.Recorder
+)
+
+// processIngested runs the processing that follows storing new live points
+// of a tenant user. The points are already stored, so failures are logged
+// rather than returned to the client.
+func processIngested(ctx context.Context, tenantID, userID string, locations []*models.Location) {
+	for _, l := range locations {
+		latestLocations.Update(*l)
+	}
+	alertStoredAnomalies(ctx, locations)
+	if err := models.AddHeatmapCounts(ctx, locations); err != nil {
+		log.Printf("heatmap rollup for tenant %s user %s failed: %v", tenantID, userID, err)
+	}
+	if err := activityRecorder.Record(ctx, tenantID, userID, locations); err != nil {
+		log.Printf("activity rollup for tenant %s user %s failed: %v", tenantID, userID, err)
+	}
+	if err := tripProcessor.Process(ctx, tenantID, userID, locations); err != nil {
+		log.Printf("trip segmentation for tenant %s user %s failed: %v", tenantID, userID, err)
+	}
+	if featureFlags.Enabled(ctx, tenantID, featureflags.Geofencing) {
+		if err := evaluateGeofences(ctx, tenantID, userID, locations); err != nil {
+			log.Printf("geofence evaluation for tenant %s user %s failed: %v", tenantID, userID, err)
+		}
+	}
+}
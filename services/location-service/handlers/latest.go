@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/cache"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// Most recent location per user, kept up to date on ingest
+var latestLocations = cache.NewLatestLocations()
+
+// LoadLatestLocations fills the latest-location cache from the database with
+// the users who submitted a point within window
+func LoadLatestLocations(ctx context.Context, window time.Duration) error {
+	locations, err := models.ListLatestLocations(ctx, time.Now().Add(-window))
+	if err != nil {
+		return err
+	}
+	latestLocations.Replace(locations)
+	return nil
+}
+
+// GetLatestLocations returns the most recent point of every user of the
+// caller's tenant. Tenant users only get their own.
+func GetLatestLocations(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	locations := []models.Location{}
+	if p.Role == "admin" {
+		locations = latestLocations.Tenant(p.TenantID)
+	} else if l, ok := latestLocations.Get(p.TenantID, p.UserID); ok {
+		locations = append(locations, l)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": locations})
+}
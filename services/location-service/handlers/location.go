@@ -1,29 +1,47 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"os"
 	"time"
 
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/featureflags"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/activity"
+	"github.com/himanshum9/go-mithril/services/location-service/anomaly"
+	"github.com/himanshum9/go-mithril/services/location-service/limiter"
 	"github.com/himanshum9/go-mithril/services/location-service/models"
 	"github.com/himanshum9/go-mithril/services/location-service/streaming"
+	"github.com/himanshum9/go-mithril/services/location-service/trips"
 )
 
 // LocationSubmissionRequest represents the payload for location submission
 type LocationSubmissionRequest struct {
-	Latitude  float64 `json:"latitude" binding:"required"`
-	Longitude float64 `json:"longitude" binding:"required"`
-	SessionID string  `json:"session_id" binding:"required"`
-	Timestamp int64   `json:"timestamp" binding:"required"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	SessionID string   `json:"session_id"`
+	Timestamp *int64   `json:"timestamp"` // Unix seconds
+	// Optional client-generated ID; retries with the same ID are not stored twice
+	ClientPointID string `json:"client_point_id,omitempty"`
+	models.Telemetry
 }
 
-// In-memory session tracker (for demo; use Redis/DB in prod)
-var sessionLastSubmission = make(map[string]int64)
-
 var (
-	kafkaBroker   string
-	kafkaStreamer *streaming.Streamer
+	appConfig    *config.Config
+	kafkaBroker  string
+	featureFlags *featureflags.Client
+
+	// Enforces the submission interval per session
+	submissionLimiter limiter.Limiter
+
+	// Publishes geofence.enter, geofence.exit and geofence.dwell events
+	geofenceStreamer *streaming.Streamer
 )
 
 func init() {
@@ -31,74 +49,175 @@ func init() {
 	if kafkaBroker == "" {
 		kafkaBroker = "localhost:9092"
 	}
-	kafkaStreamer = streaming.NewStreamer(kafkaBroker, "location-stream")
+	appConfig = config.Load()
+	geofenceStreamer = streaming.NewStreamer(kafkaBroker, appConfig.Kafka.GeofenceTopic)
+	anomalyStreamer = streaming.NewStreamer(kafkaBroker, appConfig.Kafka.AnomalyTopic)
+	featureFlags = featureflags.NewClient(appConfig.FeatureFlags.TenantServiceURL, appConfig.GetFeatureFlagRefreshInterval(), nil)
+	submissionLimiter = limiter.NewMemoryLimiter(appConfig.GetSubmissionInterval(), appConfig.GetSessionDuration())
+	tripProcessor = trips.NewProcessor(trips.ConfigFrom(appConfig.Trips))
+	activityRecorder = activity.NewRecorder(activity.ConfigFrom(appConfig.Activity))
+	anomalyConfig = anomaly.ConfigFrom(appConfig.Anomaly)
+}
+
+// SetSubmissionLimiter replaces the default in-memory submission limiter
+func SetSubmissionLimiter(l limiter.Limiter) {
+	if m, ok := submissionLimiter.(*limiter.MemoryLimiter); ok {
+		m.Close()
+	}
+	submissionLimiter = l
+}
+
+// releaseSubmissions gives back the submission intervals taken by locations
+// that could not be stored, latest first
+func releaseSubmissions(ctx context.Context, locations []*models.Location) {
+	ctx = context.WithoutCancel(ctx)
+	for i := len(locations) - 1; i >= 0; i-- {
+		l := locations[i]
+		if err := submissionLimiter.Release(ctx, l.SessionID, l.Timestamp); err != nil {
+			log.Printf("failed to release submission interval of session %s: %v", l.SessionID, err)
+		}
+	}
 }
 
 // SubmitLocation handles location data submission by tenant users
 func SubmitLocation(w http.ResponseWriter, r *http.Request) {
-	// Extract JWT claims from context (pseudo-code, adapt to your middleware)
-	claims, ok := r.Context().Value("claims").(map[string]interface{})
+	p, ok := principalFromRequest(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	role, _ := claims["role"].(string)
-	tenantID, _ := claims["tenant_id"].(string)
-	if role != "user" {
+	tenantID := p.TenantID
+	if p.Role != "user" {
 		http.Error(w, "Forbidden: Tenant users only", http.StatusForbidden)
 		return
 	}
 
 	var req LocationSubmissionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
 		return
 	}
 
-	// Session timing: allow only 10 min sessions
-	startTime := req.Timestamp - (req.Timestamp % 600)
-	if req.Timestamp < startTime || req.Timestamp > startTime+600 {
-		http.Error(w, "Session expired or invalid timestamp", http.StatusBadRequest)
+	if err := req.validate(time.Now(), appConfig.GetMaxPointAge()); err != nil {
+		var fields validation.Errors
+		if !errors.As(err, &fields) {
+			apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+			return
+		}
+		apierror.WriteValidation(w, fields)
 		return
 	}
 
-	// Interval check: allow only every 30 seconds
-	last, ok := sessionLastSubmission[req.SessionID]
-	if ok && req.Timestamp-last < 30 {
-		http.Error(w, "Submission interval too short", http.StatusTooManyRequests)
+	// Retries of an already stored point get the original result, before
+	// the interval check would reject them
+	clientPointID, err := clientPointIDFor(r, req.ClientPointID)
+	if err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	if clientPointID != "" {
+		existing, err := findSubmitted(r.Context(), p, clientPointID)
+		if err != nil {
+			http.Error(w, "Failed to check for duplicate location", http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			writeReplay(w, existing)
+			return
+		}
+	}
+
+	session, err := models.GetSession(r.Context(), tenantID, req.SessionID)
+	if err == nil && session.UserID != p.UserID {
+		err = errSessionForbidden
+	}
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	// Session timing: only accept points inside an active session
+	if status := session.StatusAt(time.Now()); status != models.SessionStatusActive {
+		http.Error(w, "Session "+status, http.StatusConflict)
+		return
+	}
+	if !session.Covers(req.Time()) {
+		http.Error(w, "Timestamp outside of session", http.StatusBadRequest)
+		return
+	}
+
+	// Privacy check: drop points the policy forbids keeping
+	policy, err := models.EffectivePrivacyPolicy(r.Context(), tenantID, p.UserID)
+	if err != nil {
+		http.Error(w, "Failed to load privacy policy", http.StatusInternalServerError)
+		return
+	}
+	if policy.Drops(req.Time()) {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": "Location discarded outside working hours"})
 		return
 	}
-	sessionLastSubmission[req.SessionID] = req.Timestamp
 
-	timestamp := time.Unix(req.Timestamp, 0)
 	location := models.Location{
-		TenantID:  tenantID,
-		Latitude:  req.Latitude,
-		Longitude: req.Longitude,
-		Timestamp: timestamp,
+		TenantID:      tenantID,
+		UserID:        p.UserID,
+		Latitude:      *req.Latitude,
+		Longitude:     *req.Longitude,
+		Timestamp:     req.Time(),
+		SessionID:     session.ID,
+		ClientPointID: clientPointID,
+		Telemetry:     req.Telemetry,
 	}
 
-	// Save location to DB
-	if err := models.SaveLocation(r.Context(), &location); err != nil {
-		http.Error(w, "Failed to save location", http.StatusInternalServerError)
+	// Anomaly check: score the point and drop it if the tenant rejects it
+	scorer, err := newAnomalyScorer(r.Context(), tenantID, p.UserID, policy)
+	if err != nil {
+		http.Error(w, "Failed to load anomaly settings", http.StatusInternalServerError)
+		return
+	}
+	if scorer.score(r.Context(), &location) {
+		apierror.Write(w, http.StatusUnprocessableEntity, apierror.CodeLocationRejected, anomalyRejection(&location))
 		return
 	}
 
-	// Stream location to Kafka
-	err := kafkaStreamer.StreamLocationData(location.TenantID, location.Latitude, location.Longitude, location.Timestamp)
+	// Interval check: allow one point per configured submission interval
+	allowed, err := submissionLimiter.Allow(r.Context(), session.ID, req.Time())
 	if err != nil {
-		// Attempt to re-establish connection and retry once
-		kafkaStreamer.Close()
-		kafkaStreamer = streaming.NewStreamer(kafkaBroker, "location-stream")
-		err = kafkaStreamer.StreamLocationData(location.TenantID, location.Latitude, location.Longitude, location.Timestamp)
-		if err != nil {
-			http.Error(w, "Failed to stream location data", http.StatusInternalServerError)
-			return
+		http.Error(w, "Failed to check submission interval", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Submission interval too short", http.StatusTooManyRequests)
+		return
+	}
+	scorer.accept(&location)
+
+	// The stored point is the one published and delivered, so the policy
+	// applies everywhere
+	location.Latitude, location.Longitude = policy.Apply(location.Latitude, location.Longitude, location.Timestamp)
+
+	// Save location to DB, queuing it for Kafka in the same transaction
+	if err := models.SaveLocation(r.Context(), &location); err != nil {
+		if errors.Is(err, models.ErrDuplicateLocation) {
+			// A concurrent retry stored the point first
+			if existing, err := findSubmitted(r.Context(), p, clientPointID); err == nil && existing != nil {
+				writeReplay(w, existing)
+				return
+			}
+		} else {
+			// The point is not stored, so its retry must not be limited
+			releaseSubmissions(r.Context(), []*models.Location{&location})
 		}
+		http.Error(w, "Failed to save location", http.StatusInternalServerError)
+		return
 	}
 
+	scorer.stored([]*models.Location{&location})
+	processIngested(r.Context(), tenantID, p.UserID, []*models.Location{&location})
+
+	// The outbox relay publishes the location to Kafka
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Location submitted and streamed", "location": location})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Location submitted and queued for streaming", "location": location})
 }
 
 // ...existing code...
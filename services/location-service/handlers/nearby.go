@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// Accepted proximity search parameters
+const (
+	defaultNearbyRadiusMeters = 5000.0
+	maxNearbyRadiusMeters     = 100000.0
+	defaultNearbyLimit        = 10
+	maxNearbyLimit            = 100
+)
+
+// NearbyLocations returns the users of the caller's tenant whose latest
+// position is closest to a point: GET /locations/nearby?lat=&lon=&radius=&limit=
+// radius is in meters. Tenant admins only.
+func NearbyLocations(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if p.Role != "admin" {
+		http.Error(w, "Forbidden: Tenant admins only", http.StatusForbidden)
+		return
+	}
+
+	values := r.URL.Query()
+	v := validation.New()
+	lat := parseFloatParam(v, values.Get("lat"), "lat")
+	lon := parseFloatParam(v, values.Get("lon"), "lon")
+	if v.Required("lat", values.Get("lat") != "") && lat != nil {
+		v.Latitude("lat", lat)
+	}
+	if v.Required("lon", values.Get("lon") != "") && lon != nil {
+		v.Longitude("lon", lon)
+	}
+	radius := defaultNearbyRadiusMeters
+	if s := values.Get("radius"); s != "" {
+		if f := parseFloatParam(v, s, "radius"); f != nil {
+			radius = *f
+			v.Range("radius", &radius, 1, maxNearbyRadiusMeters)
+		}
+	}
+	limit := defaultNearbyLimit
+	if s := values.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxNearbyLimit {
+			v.Add("limit", apierror.FieldOutOfRange, fmt.Sprintf("limit must be between 1 and %d", maxNearbyLimit))
+		}
+		limit = n
+	}
+	if fields := v.Errors(); len(fields) > 0 {
+		apierror.WriteValidation(w, fields)
+		return
+	}
+
+	nearby, err := findNearby(r.Context(), p.TenantID, geometry.Point{Lat: *lat, Lon: *lon}, radius, limit)
+	if err != nil {
+		http.Error(w, "Failed to search nearby locations", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": nearby})
+}
+
+// findNearby searches the latest positions in memory or, when configured
+// and available, with PostGIS. Sealed locations have no geography, so
+// encryption keeps the search in memory.
+func findNearby(ctx context.Context, tenantID string, center geometry.Point, radiusMeters float64, limit int) ([]models.NearbyLocation, error) {
+	if appConfig.Spatial.NearbyBackend == "postgis" && models.PostGIS && !models.EncryptionEnabled() {
+		return models.QueryNearbyLocations(ctx, tenantID, center.Lat, center.Lon, radiusMeters, limit)
+	}
+	nearby := latestLocations.Nearby(tenantID, center, radiusMeters, limit)
+	if nearby == nil {
+		nearby = []models.NearbyLocation{}
+	}
+	return nearby, nil
+}
+
+func parseFloatParam(v *validation.Validator, s, name string) *float64 {
+	if s == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.Add(name, apierror.FieldInvalid, name+" must be a number")
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import "net/http"
+
+// principal is the authenticated caller, taken from the JWT claims that the
+// auth middleware stores on the request context.
+type principal struct {
+	TenantID string
+	UserID   string
+	Role     string
+}
+
+func principalFromRequest(r *http.Request) (principal, bool) {
+	claims, ok := r.Context().Value("claims").(map[string]interface{})
+	if !ok {
+		return principal{}, false
+	}
+	p := principal{}
+	p.Role, _ = claims["role"].(string)
+	p.TenantID, _ = claims["tenant_id"].(string)
+	p.UserID, _ = claims["sub"].(string)
+	return p, true
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/privacy"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// GetPrivacyPolicy returns the privacy policy of the caller's tenant. A
+// tenant without one keeps points as sent, at any time.
+func GetPrivacyPolicy(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	policy, err := models.GetPrivacyPolicy(r.Context(), p.TenantID, "")
+	if errors.Is(err, models.ErrPrivacyPolicyNotFound) {
+		policy, err = &privacy.Policy{TenantID: p.TenantID}, nil
+	}
+	if err != nil {
+		http.Error(w, "Failed to load privacy policy", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdatePrivacyPolicy sets the coordinate precision and working hours of
+// the caller's tenant: PUT /settings/privacy
+func UpdatePrivacyPolicy(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	savePrivacyPolicy(w, r, p.TenantID, "")
+}
+
+// GetUserPrivacyPolicy returns the policy of a user that overrides the
+// tenant policy: GET /settings/privacy/users/{user_id}
+func GetUserPrivacyPolicy(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	policy, err := models.GetPrivacyPolicy(r.Context(), p.TenantID, r.PathValue("user_id"))
+	if err != nil {
+		writePrivacyPolicyError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdateUserPrivacyPolicy gives a user their own policy, which replaces the
+// tenant policy as a whole: PUT /settings/privacy/users/{user_id}
+func UpdateUserPrivacyPolicy(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	savePrivacyPolicy(w, r, p.TenantID, r.PathValue("user_id"))
+}
+
+// DeleteUserPrivacyPolicy returns a user to the tenant policy
+func DeleteUserPrivacyPolicy(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	if err := models.DeletePrivacyPolicy(r.Context(), p.TenantID, r.PathValue("user_id")); err != nil {
+		writePrivacyPolicyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TenantPrivacyPolicies serves the policies of a tenant to the streaming
+// service, which applies them before delivering points:
+// GET /privacy/tenants/{id}
+func TenantPrivacyPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := models.GetTenantPrivacyPolicies(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Failed to load privacy policies", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(policies)
+}
+
+func savePrivacyPolicy(w http.ResponseWriter, r *http.Request, tenantID, userID string) {
+	var policy privacy.Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	v := validation.New()
+	policy.Validate(v)
+	if fields := v.Errors(); len(fields) > 0 {
+		apierror.WriteValidation(w, fields)
+		return
+	}
+	policy.TenantID = tenantID
+	policy.UserID = userID
+	if err := models.SavePrivacyPolicy(r.Context(), &policy); err != nil {
+		http.Error(w, "Failed to save privacy policy", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(policy)
+}
+
+func writePrivacyPolicyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrPrivacyPolicyNotFound) {
+		http.Error(w, "Privacy policy not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Failed to access privacy policy", http.StatusInternalServerError)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// ActivityReport returns the hourly or daily activity rollups of the
+// caller's tenant:
+// GET /reports/activity?granularity=hour|day&user_id=&from=&to=&limit=&cursor=&order=
+// from and to bound the period start. Tenant users only see their own
+// activity.
+func ActivityReport(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	values := r.URL.Query()
+	params, err := pagination.ParseQuery(values, "period_start")
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	q := models.ActivityQuery{
+		Params:      params,
+		TenantID:    p.TenantID,
+		UserID:      values.Get("user_id"),
+		Granularity: values.Get("granularity"),
+	}
+	if p.Role != "admin" {
+		if q.UserID != "" && q.UserID != p.UserID {
+			writeQueryError(w, errForbiddenUser)
+			return
+		}
+		q.UserID = p.UserID
+	}
+	v := validation.New()
+	if q.Granularity == "" {
+		q.Granularity = models.ActivityDaily
+	}
+	v.OneOf("granularity", q.Granularity, models.ActivityHourly, models.ActivityDaily)
+	q.From = parseTimeParam(v, values, "from")
+	q.To = parseTimeParam(v, values, "to")
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		v.Add("to", apierror.FieldInvalid, "to must be after from")
+	}
+	if fields := v.Errors(); len(fields) > 0 {
+		apierror.WriteValidation(w, fields)
+		return
+	}
+
+	page, err := models.QueryActivity(r.Context(), q)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		writeQueryError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load activity", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
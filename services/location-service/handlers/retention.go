@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// GetRetentionSettings returns how long the caller's tenant keeps its
+// location history
+func GetRetentionSettings(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	s, err := tenantRetentionSettings(r.Context(), p.TenantID)
+	if err != nil {
+		http.Error(w, "Failed to load retention settings", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(s)
+}
+
+// UpdateRetentionSettings sets how many days the caller's tenant keeps its
+// location history, 0 for forever: PUT /settings/retention
+func UpdateRetentionSettings(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	var s models.RetentionSettings
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	v := validation.New()
+	s.Validate(v)
+	if fields := v.Errors(); len(fields) > 0 {
+		apierror.WriteValidation(w, fields)
+		return
+	}
+	s.TenantID = p.TenantID
+	if err := models.SaveRetentionSettings(r.Context(), &s); err != nil {
+		http.Error(w, "Failed to save retention settings", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(s)
+}
+
+// tenantRetentionSettings returns the settings of a tenant, defaulting to
+// LOCATION_RETENTION_DAYS
+func tenantRetentionSettings(ctx context.Context, tenantID string) (*models.RetentionSettings, error) {
+	s, err := models.GetRetentionSettings(ctx, tenantID)
+	if errors.Is(err, models.ErrRetentionSettingsNotFound) {
+		return &models.RetentionSettings{TenantID: tenantID, RetentionDays: appConfig.Retention.DefaultDays}, nil
+	}
+	return s, err
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// StartSession opens a tracking session for the calling tenant user. The
+// session expires after the configured session duration.
+func StartSession(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if p.Role != "user" {
+		http.Error(w, "Forbidden: Tenant users only", http.StatusForbidden)
+		return
+	}
+
+	session := models.NewSession(p.TenantID, p.UserID, time.Now().UTC(), appConfig.GetSessionDuration())
+	if err := models.SaveSession(r.Context(), session); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// EndSession closes a session before it expires
+func EndSession(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := ownedSession(r, p)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	session, err = models.EndSession(r.Context(), p.TenantID, session.ID, time.Now().UTC())
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	if err := submissionLimiter.Forget(r.Context(), session.ID); err != nil {
+		log.Printf("failed to clear submission interval for session %s: %v", session.ID, err)
+	}
+
+	json.NewEncoder(w).Encode(session)
+}
+
+// GetSession returns a session of the caller's tenant
+func GetSession(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := ownedSession(r, p)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(session)
+}
+
+var errSessionForbidden = errors.New("session belongs to another user")
+
+// ownedSession loads the session named in the path. Tenant users may only
+// see their own sessions; admins may see any session of their tenant.
+func ownedSession(r *http.Request, p principal) (*models.Session, error) {
+	session, err := models.GetSession(r.Context(), p.TenantID, r.PathValue("id"))
+	if err != nil {
+		return nil, err
+	}
+	if p.Role != "admin" && session.UserID != p.UserID {
+		return nil, errSessionForbidden
+	}
+	return session, nil
+}
+
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrSessionNotFound), errors.Is(err, errSessionForbidden):
+		http.Error(w, "Session not found", http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to load session", http.StatusInternalServerError)
+	}
+}
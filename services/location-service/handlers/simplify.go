@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// Accepted simplification parameters
+const (
+	maxSimplifyToleranceMeters = 10000.0
+	maxBucket                  = 24 * time.Hour
+)
+
+// trackReduction is the simplification requested with
+// ?simplify_tolerance_m=&bucket=. Bucket downsampling runs first.
+type trackReduction struct {
+	ToleranceMeters float64
+	Bucket          time.Duration
+}
+
+func (t trackReduction) enabled() bool {
+	return t.ToleranceMeters > 0 || t.Bucket > 0
+}
+
+func parseTrackReduction(v *validation.Validator, values url.Values) trackReduction {
+	var t trackReduction
+	if s := values.Get("simplify_tolerance_m"); s != "" {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			v.Add("simplify_tolerance_m", apierror.FieldInvalid, "simplify_tolerance_m must be a number of meters")
+		} else if !(f > 0 && f <= maxSimplifyToleranceMeters) {
+			v.Add("simplify_tolerance_m", apierror.FieldOutOfRange, fmt.Sprintf("simplify_tolerance_m must be greater than 0 and at most %g", maxSimplifyToleranceMeters))
+		} else {
+			t.ToleranceMeters = f
+		}
+	}
+	if s := values.Get("bucket"); s != "" {
+		// Durations such as 30s or 5m, or plain seconds
+		d, err := time.ParseDuration(s)
+		if secs, convErr := strconv.Atoi(s); convErr == nil {
+			d, err = time.Duration(secs)*time.Second, nil
+		}
+		if err != nil {
+			v.Add("bucket", apierror.FieldInvalid, "bucket must be a duration such as 30s, 5m or 1h")
+		} else if d < time.Second || d > maxBucket {
+			v.Add("bucket", apierror.FieldOutOfRange, "bucket must be between 1s and 24h")
+		} else {
+			t.Bucket = d
+		}
+	}
+	return t
+}
+
+// apply reduces every track, the points of one user and session, on its
+// own. The order of the remaining points is kept.
+func (t trackReduction) apply(locations []models.Location) []models.Location {
+	type trackKey struct{ userID, sessionID string }
+	tracks := make(map[trackKey][]int)
+	var order []trackKey
+	for i, l := range locations {
+		k := trackKey{l.UserID, l.SessionID}
+		if _, ok := tracks[k]; !ok {
+			order = append(order, k)
+		}
+		tracks[k] = append(tracks[k], i)
+	}
+
+	keep := make([]bool, len(locations))
+	for _, k := range order {
+		indices := tracks[k]
+		if t.Bucket > 0 {
+			times := make([]time.Time, len(indices))
+			for i, idx := range indices {
+				times[i] = locations[idx].Timestamp
+			}
+			kept := geometry.Downsample(times, t.Bucket)
+			for i, j := range kept {
+				kept[i] = indices[j]
+			}
+			indices = kept
+		}
+		if t.ToleranceMeters > 0 {
+			points := make([]geometry.Point, len(indices))
+			for i, idx := range indices {
+				points[i] = geometry.Point{Lat: locations[idx].Latitude, Lon: locations[idx].Longitude}
+			}
+			kept := geometry.Simplify(points, t.ToleranceMeters)
+			for i, j := range kept {
+				kept[i] = indices[j]
+			}
+			indices = kept
+		}
+		for _, idx := range indices {
+			keep[idx] = true
+		}
+	}
+
+	reduced := make([]models.Location, 0, len(locations))
+	for i, l := range locations {
+		if keep[i] {
+			reduced = append(reduced, l)
+		}
+	}
+	return reduced
+}
+
+var errTooManyPoints = errors.New("Too many points to simplify, narrow the time range")
+
+// loadReduced reads every location matching q and reduces the tracks.
+// Simplification needs whole tracks, so the result is not paginated.
+func loadReduced(ctx context.Context, q models.LocationQuery, t trackReduction) ([]models.Location, error) {
+	max := appConfig.Ingest.SimplifyMaxPoints
+	var locations []models.Location
+	err := models.EachLocation(ctx, q, func(l models.Location) error {
+		if len(locations) >= max {
+			return errTooManyPoints
+		}
+		locations = append(locations, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.apply(locations), nil
+}
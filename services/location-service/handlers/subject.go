@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/himanshum9/go-mithril/pkg/privacy"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+	"github.com/himanshum9/go-mithril/services/location-service/streaming"
+)
+
+// locationStreamer writes to the location topic the outbox relay publishes
+// to, so erasure tombstones follow the user's locations
+var locationStreamer *streaming.Streamer
+
+// SetLocationStreamer sets the streamer of the location topic
+func SetLocationStreamer(s *streaming.Streamer) {
+	locationStreamer = s
+}
+
+// ExportUserData returns everything held about a user of the caller's
+// tenant as one JSON document, for subject access requests:
+// GET /users/{user_id}/data
+// Tenant admins may export any user, tenant users only themselves.
+func ExportUserData(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID := r.PathValue("user_id")
+	if p.Role != "admin" && userID != p.UserID {
+		http.Error(w, errForbiddenUser.Error(), http.StatusForbidden)
+		return
+	}
+	ctx := r.Context()
+
+	// The small sections are loaded first, so failures can still be
+	// answered with an error status
+	profile, err := models.GetUserProfile(ctx, p.TenantID, userID)
+	deliveries := []models.Delivery{}
+	if err == nil && profile != nil {
+		deliveries, err = models.ListUserDeliveries(ctx, profile.ID)
+	}
+	var sessions []models.Session
+	if err == nil {
+		sessions, err = models.ListUserSessions(ctx, p.TenantID, userID)
+	}
+	var importJobs []models.ImportJob
+	if err == nil {
+		importJobs, err = models.ListUserImportJobs(ctx, p.TenantID, userID)
+	}
+	var policy *privacy.Policy
+	if err == nil {
+		policy, err = models.GetPrivacyPolicy(ctx, p.TenantID, userID)
+		if errors.Is(err, models.ErrPrivacyPolicyNotFound) {
+			err = nil
+		}
+	}
+	if err != nil {
+		http.Error(w, "Failed to export user data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="user-`+safeFilename(userID)+`.json"`)
+	doc := &jsonObject{w: w}
+	doc.field("tenant_id", p.TenantID)
+	doc.field("user_id", userID)
+	doc.field("exported_at", time.Now().UTC())
+	doc.field("profile", profile)
+	doc.field("privacy_policy", policy)
+	doc.field("sessions", sessions)
+	doc.field("import_jobs", importJobs)
+	doc.field("deliveries", deliveries)
+	doc.array("locations", func(add func(interface{}) error) error {
+		return models.EachLocation(ctx, models.LocationQuery{TenantID: p.TenantID, UserID: userID}, func(l models.Location) error {
+			return add(l)
+		})
+	})
+	doc.array("trips", eachPage(func(cursor *pagination.Cursor) (*pagination.Page[models.Trip], error) {
+		return models.QueryTrips(ctx, models.SegmentQuery{Params: exportPage(cursor), TenantID: p.TenantID, UserID: userID})
+	}))
+	doc.array("stops", eachPage(func(cursor *pagination.Cursor) (*pagination.Page[models.Stop], error) {
+		return models.QueryStops(ctx, models.SegmentQuery{Params: exportPage(cursor), TenantID: p.TenantID, UserID: userID})
+	}))
+	doc.array("geofence_events", eachPage(func(cursor *pagination.Cursor) (*pagination.Page[models.GeofenceEvent], error) {
+		return models.QueryGeofenceEvents(ctx, models.GeofenceEventQuery{Params: exportPage(cursor), TenantID: p.TenantID, UserID: userID})
+	}))
+	for _, rollup := range []struct{ name, granularity string }{
+		{"activity_hourly", models.ActivityHourly},
+		{"activity_daily", models.ActivityDaily},
+	} {
+		doc.array(rollup.name, eachPage(func(cursor *pagination.Cursor) (*pagination.Page[models.ActivityRollup], error) {
+			return models.QueryActivity(ctx, models.ActivityQuery{Params: exportPage(cursor), TenantID: p.TenantID, UserID: userID, Granularity: rollup.granularity})
+		}))
+	}
+	if err := doc.close(); err != nil {
+		// Headers are already sent; the client sees a truncated document
+		log.Printf("user data export for tenant %s failed: %v", p.TenantID, err)
+	}
+}
+
+// EraseUserData deletes everything held about a user of the caller's
+// tenant, publishes tombstones for the user to the location, geofence and
+// anomaly topics, and returns the erasure certificate:
+// DELETE /users/{user_id}/data
+// When publishing fails the data is already gone and the certificate stays
+// pending; repeating the request completes it.
+func EraseUserData(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	userID := r.PathValue("user_id")
+	c, err := models.EraseUser(r.Context(), p.TenantID, userID, p.UserID)
+	if err != nil {
+		http.Error(w, "Failed to erase user data", http.StatusInternalServerError)
+		return
+	}
+	latestLocations.Remove(p.TenantID, userID)
+	scoredLocations.Remove(p.TenantID, userID)
+
+	topics := []string{}
+	for _, s := range []*streaming.Streamer{locationStreamer, geofenceStreamer, anomalyStreamer} {
+		if err := s.PublishTombstone(r.Context(), p.TenantID, userID); err != nil {
+			http.Error(w, "Failed to publish erasure tombstones, repeat the request to complete erasure "+c.ID, http.StatusInternalServerError)
+			return
+		}
+		topics = append(topics, s.Topic())
+	}
+	if err := models.CompleteErasure(r.Context(), c, topics); err != nil {
+		http.Error(w, "Failed to record erasure", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(c)
+}
+
+// GetErasure returns the certificate of an erasure of the caller's tenant:
+// GET /erasures/{id}
+func GetErasure(w http.ResponseWriter, r *http.Request) {
+	p, ok := settingsPrincipal(w, r)
+	if !ok {
+		return
+	}
+	c, err := models.GetErasure(r.Context(), p.TenantID, r.PathValue("id"))
+	if errors.Is(err, models.ErrErasureNotFound) {
+		http.Error(w, "Erasure not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load erasure", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(c)
+}
+
+// ForgetErasedUsers drops the users erased through any replica from the
+// latest-location cache of this one, checking every interval
+func ForgetErasedUsers(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	since := time.Now().Add(-interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// Erasures are committed after they are requested; looking back one
+		// more interval catches those committed since the last check
+		now := time.Now()
+		erasures, err := models.ListErasuresSince(ctx, since)
+		if err != nil {
+			log.Printf("failed to list erasures: %v", err)
+			continue
+		}
+		for _, e := range erasures {
+			latestLocations.Remove(e.TenantID, e.UserID)
+			scoredLocations.Remove(e.TenantID, e.UserID)
+		}
+		since = now.Add(-interval)
+	}
+}
+
+// exportPage returns the parameters of a full page after cursor
+func exportPage(cursor *pagination.Cursor) pagination.Params {
+	return pagination.Params{Limit: pagination.MaxLimit, Cursor: cursor, Order: pagination.OrderAsc}
+}
+
+// eachPage adapts a paged query to jsonObject.array, following the cursors
+// of the pages fetch returns
+func eachPage[T any](fetch func(cursor *pagination.Cursor) (*pagination.Page[T], error)) func(add func(interface{}) error) error {
+	return func(add func(interface{}) error) error {
+		var cursor *pagination.Cursor
+		for {
+			page, err := fetch(cursor)
+			if err != nil {
+				return err
+			}
+			for _, item := range page.Items {
+				if err := add(item); err != nil {
+					return err
+				}
+			}
+			if page.NextCursor == "" {
+				return nil
+			}
+			if cursor, err = pagination.DecodeCursor(page.NextCursor); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// jsonObject writes a JSON object member by member, so arrays can be
+// streamed without holding them in memory. Writing stops at the first
+// error, which close returns.
+type jsonObject struct {
+	w   io.Writer
+	n   int
+	err error
+}
+
+func (o *jsonObject) key(name string) {
+	if o.err != nil {
+		return
+	}
+	sep := ","
+	if o.n == 0 {
+		sep = "{"
+	}
+	o.n++
+	k, _ := json.Marshal(name)
+	_, o.err = fmt.Fprintf(o.w, "%s%s:", sep, k)
+}
+
+func (o *jsonObject) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = o.w.Write(b)
+	return err
+}
+
+func (o *jsonObject) field(name string, v interface{}) {
+	o.key(name)
+	if o.err == nil {
+		o.err = o.write(v)
+	}
+}
+
+// array writes the values each passes to add as an array
+func (o *jsonObject) array(name string, each func(add func(interface{}) error) error) {
+	o.key(name)
+	if o.err != nil {
+		return
+	}
+	if _, o.err = io.WriteString(o.w, "["); o.err != nil {
+		return
+	}
+	first := true
+	o.err = each(func(v interface{}) error {
+		if !first {
+			if _, err := io.WriteString(o.w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		return o.write(v)
+	})
+	if o.err == nil {
+		_, o.err = io.WriteString(o.w, "]")
+	}
+}
+
+func (o *jsonObject) close() error {
+	if o.err != nil {
+		return o.err
+	}
+	if o.n == 0 {
+		_, o.err = io.WriteString(o.w, "{}\n")
+		return o.err
+	}
+	_, o.err = io.WriteString(o.w, "}\n")
+	return o.err
+}
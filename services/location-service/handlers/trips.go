@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// ListTrips returns the completed trips of the caller's tenant:
+// GET /trips?user_id=&from=&to=&limit=&cursor=&order=
+// Tenant users only see their own trips.
+func ListTrips(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	q, err := segmentQueryFromRequest(r, p)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	page, err := models.QueryTrips(r.Context(), q)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		writeQueryError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to list trips", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// ListStops returns the completed stops of the caller's tenant:
+// GET /stops?user_id=&from=&to=&limit=&cursor=&order=
+// Tenant users only see their own stops.
+func ListStops(w http.ResponseWriter, r *http.Request) {
+	p, ok := principalFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	q, err := segmentQueryFromRequest(r, p)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	page, err := models.QueryStops(r.Context(), q)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		writeQueryError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to list stops", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// segmentQueryFromRequest reads the filters shared by the trip and stop
+// listings; from and to bound the start time
+func segmentQueryFromRequest(r *http.Request, p principal) (models.SegmentQuery, error) {
+	values := r.URL.Query()
+	params, err := pagination.ParseQuery(values, "started_at")
+	if err != nil {
+		return models.SegmentQuery{}, err
+	}
+	q := models.SegmentQuery{Params: params, TenantID: p.TenantID, UserID: values.Get("user_id")}
+	if p.Role != "admin" {
+		if q.UserID != "" && q.UserID != p.UserID {
+			return q, errForbiddenUser
+		}
+		q.UserID = p.UserID
+	}
+	v := validation.New()
+	q.From = parseTimeParam(v, values, "from")
+	q.To = parseTimeParam(v, values, "to")
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		v.Add("to", apierror.FieldInvalid, "to must be after from")
+	}
+	return q, v.Err()
+}
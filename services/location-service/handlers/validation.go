@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/validation"
+)
+
+// validate checks a submitted point against coordinate ranges and server
+// time. Points may never be ahead of server time by more than the allowed
+// clock skew; maxAge additionally bounds how old they may be, zero meaning
+// any age.
+func (req *LocationSubmissionRequest) validate(now time.Time, maxAge time.Duration) error {
+	v := validation.New()
+	if v.Required("latitude", req.Latitude != nil) {
+		v.Latitude("latitude", req.Latitude)
+	}
+	if v.Required("longitude", req.Longitude != nil) {
+		v.Longitude("longitude", req.Longitude)
+	}
+	v.Required("session_id", req.SessionID != "")
+	if v.Required("timestamp", req.Timestamp != nil) {
+		ts := req.Time()
+		v.Timestamp("timestamp", &ts, now, maxAge, appConfig.GetMaxClockSkew())
+	}
+	v.MaxLength("client_point_id", req.ClientPointID, maxClientPointIDLength)
+	req.Telemetry.Validate(v)
+	return v.Err()
+}
+
+// Time returns the point timestamp. It must only be called on a validated
+// request.
+func (req *LocationSubmissionRequest) Time() time.Time {
+	return time.Unix(*req.Timestamp, 0)
+}
@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumns maps accepted header names to the location field they fill.
+// The names written by the export endpoint are accepted as they are.
+var csvColumns = map[string]string{
+	"latitude":        "latitude",
+	"lat":             "latitude",
+	"longitude":       "longitude",
+	"lon":             "longitude",
+	"lng":             "longitude",
+	"timestamp":       "timestamp",
+	"time":            "timestamp",
+	"accuracy":        "accuracy",
+	"altitude":        "altitude",
+	"ele":             "altitude",
+	"elevation":       "altitude",
+	"speed":           "speed",
+	"heading":         "heading",
+	"course":          "heading",
+	"bearing":         "heading",
+	"battery_level":   "battery_level",
+	"battery":         "battery_level",
+	"provider":        "provider",
+	"client_point_id": "client_point_id",
+}
+
+// ParseCSV reads a CSV file with a header row. Columns are matched by name,
+// case-insensitively; unknown columns are ignored.
+func ParseCSV(r io.Reader, fn func(Record) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("missing CSV header row")
+		}
+		return err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		if field, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			if _, dup := index[field]; !dup {
+				index[field] = i
+			}
+		}
+	}
+	for _, field := range []string{"latitude", "longitude", "timestamp"} {
+		if _, ok := index[field]; !ok {
+			return fmt.Errorf("missing CSV column %s", field)
+		}
+	}
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// A malformed row only loses that row
+			if err := fn(Record{Line: parseErr.StartLine, Err: parseErr.Err}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		rec := Record{Line: line}
+		rec.Err = fillCSVRow(&rec, row, index)
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+func fillCSVRow(rec *Record, row []string, index map[string]int) error {
+	get := func(field string) string {
+		i, ok := index[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	l := &rec.Location
+	var err error
+	if l.Latitude, err = parseFloat("latitude", get("latitude")); err != nil {
+		return err
+	}
+	if l.Longitude, err = parseFloat("longitude", get("longitude")); err != nil {
+		return err
+	}
+	ts := get("timestamp")
+	if ts == "" {
+		return errors.New("timestamp is required")
+	}
+	if l.Timestamp, err = parseTime(ts); err != nil {
+		return err
+	}
+	optional := []struct {
+		field string
+		dst   **float64
+	}{
+		{"accuracy", &l.Accuracy},
+		{"altitude", &l.Altitude},
+		{"speed", &l.Speed},
+		{"heading", &l.Heading},
+		{"battery_level", &l.BatteryLevel},
+	}
+	for _, o := range optional {
+		if *o.dst, err = optionalFloat(o.field, get(o.field)); err != nil {
+			return err
+		}
+	}
+	l.Provider = get("provider")
+	l.ClientPointID = get("client_point_id")
+	return nil
+}
@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+type geoJSONFeature struct {
+	Geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+// geoJSONProperties maps the properties written by the export endpoint.
+// Line features carry per-vertex times in coordTimes, the convention of
+// most GPX to GeoJSON converters, or times.
+type geoJSONProperties struct {
+	Timestamp     json.RawMessage   `json:"timestamp"`
+	Time          json.RawMessage   `json:"time"`
+	CoordTimes    []json.RawMessage `json:"coordTimes"`
+	Times         []json.RawMessage `json:"times"`
+	ClientPointID string            `json:"client_point_id"`
+	models.Telemetry
+}
+
+// ParseGeoJSON reads the Point, MultiPoint and LineString features of a
+// FeatureCollection. LineStrings without per-vertex times, such as the
+// track line written by the export endpoint, carry no timestamps and are
+// skipped.
+func ParseGeoJSON(r io.Reader, fn func(Record) error) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return errors.New("expected a GeoJSON FeatureCollection")
+	}
+	found := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != "features" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		found = true
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return errors.New("features must be an array")
+		}
+		for dec.More() {
+			line := lineAt(data, dec.InputOffset())
+			var f geoJSONFeature
+			if err := dec.Decode(&f); err != nil {
+				return err
+			}
+			if err := f.records(line, fn); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return errors.New("expected a GeoJSON FeatureCollection")
+	}
+	return nil
+}
+
+// lineAt returns the line of the first value at or after offset
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+			continue
+		}
+		break
+	}
+	return 1 + bytes.Count(data[:offset], []byte{'\n'})
+}
+
+func (f *geoJSONFeature) records(line int, fn func(Record) error) error {
+	switch f.Geometry.Type {
+	case "Point":
+		rec := Record{Line: line}
+		var pos []float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &pos); err != nil {
+			rec.Err = fmt.Errorf("invalid Point coordinates: %v", err)
+			return fn(rec)
+		}
+		ts := f.Properties.Timestamp
+		if len(ts) == 0 {
+			ts = f.Properties.Time
+		}
+		rec.Location.Telemetry = f.Properties.Telemetry
+		rec.Location.ClientPointID = f.Properties.ClientPointID
+		rec.Err = fillPosition(&rec.Location, pos, ts)
+		return fn(rec)
+	case "MultiPoint", "LineString":
+		times := f.Properties.CoordTimes
+		if times == nil {
+			times = f.Properties.Times
+		}
+		if times == nil {
+			return nil
+		}
+		var positions [][]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &positions); err != nil {
+			return fn(Record{Line: line, Err: fmt.Errorf("invalid %s coordinates: %v", f.Geometry.Type, err)})
+		}
+		if len(times) != len(positions) {
+			return fn(Record{Line: line, Err: fmt.Errorf("%d coordinates but %d times", len(positions), len(times))})
+		}
+		for i, pos := range positions {
+			rec := Record{Line: line}
+			if err := fillPosition(&rec.Location, pos, times[i]); err != nil {
+				rec.Err = fmt.Errorf("coordinates[%d]: %v", i, err)
+			}
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// fillPosition sets the coordinates, altitude and time of l from a GeoJSON
+// position and a timestamp property
+func fillPosition(l *models.Location, pos []float64, ts json.RawMessage) error {
+	if len(pos) < 2 {
+		return errors.New("position needs longitude and latitude")
+	}
+	l.Longitude, l.Latitude = pos[0], pos[1]
+	if len(pos) > 2 {
+		alt := pos[2]
+		l.Altitude = &alt
+	}
+	if len(ts) == 0 || string(ts) == "null" {
+		return errors.New("timestamp is required")
+	}
+	// Accept both "2024-01-02T03:04:05Z" and 1704164645
+	var s string
+	if err := json.Unmarshal(ts, &s); err != nil {
+		s = string(ts)
+	}
+	t, err := parseTime(s)
+	if err != nil {
+		return err
+	}
+	l.Timestamp = t
+	return nil
+}
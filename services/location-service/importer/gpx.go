@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// gpxPoint holds the elements of a trkpt, rtept or wpt. Speed and course
+// only exist in GPX 1.0 but are still written by many devices.
+type gpxPoint struct {
+	Lat    string `xml:"lat,attr"`
+	Lon    string `xml:"lon,attr"`
+	Ele    string `xml:"ele"`
+	Time   string `xml:"time"`
+	Speed  string `xml:"speed"`
+	Course string `xml:"course"`
+}
+
+// ParseGPX reads the track, route and waypoint points of a GPX 1.0 or 1.1
+// file, streaming the document so large files are not held in memory
+func ParseGPX(r io.Reader, fn func(Record) error) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "trkpt", "rtept", "wpt":
+		default:
+			continue
+		}
+		line, _ := dec.InputPos()
+		var pt gpxPoint
+		if err := dec.DecodeElement(&pt, &start); err != nil {
+			return err
+		}
+		rec := Record{Line: line}
+		rec.Err = pt.fill(&rec)
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+func (pt *gpxPoint) fill(rec *Record) error {
+	l := &rec.Location
+	var err error
+	if l.Latitude, err = parseFloat("lat", pt.Lat); err != nil {
+		return err
+	}
+	if l.Longitude, err = parseFloat("lon", pt.Lon); err != nil {
+		return err
+	}
+	if pt.Time == "" {
+		return errors.New("time is required")
+	}
+	if l.Timestamp, err = parseTime(pt.Time); err != nil {
+		return err
+	}
+	if l.Altitude, err = optionalFloat("ele", pt.Ele); err != nil {
+		return err
+	}
+	if l.Speed, err = optionalFloat("speed", pt.Speed); err != nil {
+		return err
+	}
+	if l.Heading, err = optionalFloat("course", pt.Course); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseFloat(name, s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("%s is required", name)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return f, nil
+}
+
+func optionalFloat(name, s string) (*float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	f, err := parseFloat(name, s)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
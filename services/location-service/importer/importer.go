@@ -0,0 +1,100 @@
+// Package importer loads historical tracks exported by other tracking
+// systems as GPX, GeoJSON or CSV.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// Record is one point read from an import file. Line is the line of the
+// file the point was read from. Err is set when the point could not be
+// parsed; Location is then incomplete.
+type Record struct {
+	Line     int
+	Location models.Location
+	Err      error
+}
+
+// Parser reads r and calls fn for every point in file order. A returned
+// error means the file as a whole could not be read.
+type Parser func(r io.Reader, fn func(Record) error) error
+
+// Format is a track file format accepted for import
+type Format struct {
+	Name         string
+	Extensions   []string
+	ContentTypes []string
+	Parse        Parser
+}
+
+var formats = []Format{
+	{Name: "gpx", Extensions: []string{".gpx"}, ContentTypes: []string{"application/gpx+xml"}, Parse: ParseGPX},
+	{Name: "geojson", Extensions: []string{".geojson", ".json"}, ContentTypes: []string{"application/geo+json", "application/json"}, Parse: ParseGeoJSON},
+	{Name: "csv", Extensions: []string{".csv"}, ContentTypes: []string{"text/csv"}, Parse: ParseCSV},
+}
+
+// Lookup returns the format with the given name
+func Lookup(name string) (Format, bool) {
+	for _, f := range formats {
+		if f.Name == strings.ToLower(name) {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// Detect picks a format from a file name extension, falling back to a
+// Content-Type header
+func Detect(filename, contentType string) (Format, bool) {
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" {
+		for _, f := range formats {
+			for _, e := range f.Extensions {
+				if e == ext {
+					return f, true
+				}
+			}
+		}
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return Format{}, false
+	}
+	for _, f := range formats {
+		for _, ct := range f.ContentTypes {
+			if ct == mediaType {
+				return f, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// Names lists the supported format names
+func Names() []string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// parseTime accepts RFC 3339 or Unix seconds, the forms written by the
+// export endpoint and most tracking vendors
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q, expected RFC 3339 or Unix seconds", s)
+}
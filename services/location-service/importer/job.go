@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/privacy"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// DefaultBatchSize is the number of points copied per statement
+const DefaultBatchSize = 1000
+
+// Options controls how an import job stores points
+type Options struct {
+	BatchSize int
+	// Points may be ahead of server time by at most MaxClockSkew
+	MaxClockSkew time.Duration
+	// Stored is called with the points of every batch that were inserted
+	Stored func(locations []*models.Location)
+	// Progress is called after every stored batch
+	Progress func(job *models.ImportJob)
+	// Privacy is the policy of the job's user; points it drops are counted
+	// as discarded and the others stored with its precision
+	Privacy *privacy.Policy
+}
+
+// Run parses r and stores its points for the job's tenant user, updating
+// the job after every batch. Points already imported, either earlier in the
+// file or by a previous import, are counted as duplicates. Point IDs are
+// derived before the privacy policy applies, so a reimport still matches
+// the stored points. The job is
+// finished, and stored, before Run returns.
+func Run(ctx context.Context, job *models.ImportJob, format Format, r io.Reader, opts Options) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	job.Status = models.ImportStatusRunning
+	if err := models.UpdateImportJob(ctx, job); err != nil {
+		return err
+	}
+
+	counter := &countingReader{r: r}
+	seen := make(map[string]struct{})
+	batch := make([]*models.Location, 0, opts.BatchSize)
+	flush := func() error {
+		if err := storeBatch(ctx, job, batch, opts); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		job.ReadBytes = counter.n
+		if err := models.UpdateImportJob(ctx, job); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			opts.Progress(job)
+		}
+		return nil
+	}
+
+	now := time.Now()
+	err := format.Parse(counter, func(rec Record) error {
+		job.Processed++
+		if rec.Err == nil {
+			rec.Err = validate(&rec.Location, now, opts.MaxClockSkew)
+		}
+		if rec.Err != nil {
+			job.AddError(rec.Line, rec.Err)
+			return nil
+		}
+
+		l := rec.Location
+		l.TenantID = job.TenantID
+		l.UserID = job.UserID
+		l.SessionID = ""
+		if l.ClientPointID == "" {
+			l.ClientPointID = pointID(&l)
+		}
+		if _, dup := seen[l.ClientPointID]; dup {
+			job.Duplicates++
+			return nil
+		}
+		seen[l.ClientPointID] = struct{}{}
+		if opts.Privacy.Drops(l.Timestamp) {
+			job.Discarded++
+			return nil
+		}
+		l.Latitude, l.Longitude = opts.Privacy.Apply(l.Latitude, l.Longitude, l.Timestamp)
+
+		batch = append(batch, &l)
+		if len(batch) < opts.BatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	job.ReadBytes = counter.n
+	job.Finish(err)
+	if updateErr := models.UpdateImportJob(context.Background(), job); updateErr != nil {
+		log.Printf("failed to store import job %s: %v", job.ID, updateErr)
+	}
+	return err
+}
+
+func storeBatch(ctx context.Context, job *models.ImportJob, batch []*models.Location, opts Options) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	// Replayed points are queued in the outbox with the batch
+	inserted, err := models.CopyLocations(ctx, batch, job.Replay)
+	if err != nil {
+		return err
+	}
+	job.Imported += inserted
+	job.Duplicates += len(batch) - inserted
+
+	if inserted == 0 {
+		return nil
+	}
+	stored := make([]*models.Location, 0, inserted)
+	for _, l := range batch {
+		if l.ID != 0 {
+			stored = append(stored, l)
+		}
+	}
+	// The points are stored; a missed rollup is repaired with rollup-heatmap
+	if err := models.AddHeatmapCounts(ctx, stored); err != nil {
+		log.Printf("heatmap rollup for import job %s failed: %v", job.ID, err)
+	}
+	if opts.Stored != nil {
+		opts.Stored(stored)
+	}
+	return nil
+}
+
+// validate applies the submission checks, without a lower bound on age
+func validate(l *models.Location, now time.Time, maxSkew time.Duration) error {
+	v := validation.New()
+	v.Latitude("latitude", &l.Latitude)
+	v.Longitude("longitude", &l.Longitude)
+	v.Timestamp("timestamp", &l.Timestamp, now, 0, maxSkew)
+	v.MaxLength("client_point_id", l.ClientPointID, 255)
+	l.Telemetry.Validate(v)
+	return v.Err()
+}
+
+// pointID derives a client point ID from the time and position of l, so
+// importing the same file twice stores every point once
+func pointID(l *models.Location) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%.7f|%.7f", l.Timestamp.UnixNano(), l.Latitude, l.Longitude)))
+	return "import-" + hex.EncodeToString(sum[:16])
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
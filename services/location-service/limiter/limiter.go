@@ -0,0 +1,22 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter enforces a minimum interval between accepted submissions for a
+// key, typically a tracking session ID. Implementations must be safe for
+// concurrent use.
+type Limiter interface {
+	// Allow records a submission for key at time at and reports whether it
+	// was accepted. A submission is rejected if it is less than the interval
+	// after the last accepted one, or earlier than it.
+	Allow(ctx context.Context, key string, at time.Time) (bool, error)
+	// Release undoes the submission Allow accepted for key at time at when
+	// it could not be stored, so a retry of it is accepted. Only the latest
+	// submission of a key is released, and its interval starts over.
+	Release(ctx context.Context, key string, at time.Time) error
+	// Forget drops the state kept for key
+	Forget(ctx context.Context, key string) error
+}
@@ -0,0 +1,137 @@
+package limiter
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const shardCount = 32
+
+// MemoryLimiter keeps the last accepted submission per key in process
+// memory. Keys are spread over shards to reduce lock contention and are
+// evicted once they have been idle for the TTL. It only enforces the
+// interval within a single replica.
+type MemoryLimiter struct {
+	interval time.Duration
+	ttl      time.Duration
+	shards   [shardCount]*shard
+	stop     chan struct{}
+	once     sync.Once
+}
+
+type shard struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	last     time.Time
+	expireAt time.Time
+}
+
+// NewMemoryLimiter returns a limiter that evicts keys idle for longer than
+// ttl. A ttl shorter than the interval is raised to the interval; without
+// either, keys are kept until they are forgotten.
+func NewMemoryLimiter(interval, ttl time.Duration) *MemoryLimiter {
+	if ttl < interval {
+		ttl = interval
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	l := &MemoryLimiter{
+		interval: interval,
+		ttl:      ttl,
+		stop:     make(chan struct{}),
+	}
+	for i := range l.shards {
+		l.shards[i] = &shard{entries: make(map[string]entry)}
+	}
+	if ttl > 0 {
+		go l.evictLoop()
+	}
+	return l
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, at time.Time) (bool, error) {
+	s := l.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && at.Sub(e.last) < l.interval {
+		return false, nil
+	}
+	e := entry{last: at}
+	if l.ttl > 0 {
+		e.expireAt = time.Now().Add(l.ttl)
+	}
+	s.entries[key] = e
+	return true, nil
+}
+
+func (l *MemoryLimiter) Release(ctx context.Context, key string, at time.Time) error {
+	s := l.shardFor(key)
+	s.mu.Lock()
+	if e, ok := s.entries[key]; ok && e.last.Equal(at) {
+		delete(s.entries, key)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (l *MemoryLimiter) Forget(ctx context.Context, key string) error {
+	s := l.shardFor(key)
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of tracked keys
+func (l *MemoryLimiter) Len() int {
+	n := 0
+	for _, s := range l.shards {
+		s.mu.Lock()
+		n += len(s.entries)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Close stops the eviction goroutine
+func (l *MemoryLimiter) Close() {
+	l.once.Do(func() { close(l.stop) })
+}
+
+func (l *MemoryLimiter) shardFor(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return l.shards[h.Sum32()%shardCount]
+}
+
+func (l *MemoryLimiter) evictLoop() {
+	ticker := time.NewTicker(max(l.ttl/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			l.evictExpired(now)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+func (l *MemoryLimiter) evictExpired(now time.Time) {
+	for _, s := range l.shards {
+		s.mu.Lock()
+		for key, e := range s.entries {
+			if !e.expireAt.IsZero() && now.After(e.expireAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
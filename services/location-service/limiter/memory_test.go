@@ -0,0 +1,158 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiterAllow(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter(30*time.Second, time.Minute)
+	defer l.Close()
+
+	steps := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"first submission", base, true},
+		{"inside the interval", base.Add(29 * time.Second), false},
+		{"at the interval", base.Add(30 * time.Second), true},
+		{"earlier than the last accepted", base.Add(10 * time.Second), false},
+		{"after the interval", base.Add(61 * time.Second), true},
+	}
+	for _, s := range steps {
+		got, err := l.Allow(context.Background(), "session", s.at)
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if got != s.want {
+			t.Errorf("%s: Allow = %v, want %v", s.name, got, s.want)
+		}
+	}
+}
+
+func TestMemoryLimiterConcurrentSameKey(t *testing.T) {
+	l := NewMemoryLimiter(30*time.Second, time.Minute)
+	defer l.Close()
+
+	at := time.Now()
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := l.Allow(context.Background(), "session", at)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("accepted %d concurrent submissions of one session, want 1", n)
+	}
+}
+
+func TestMemoryLimiterConcurrentKeys(t *testing.T) {
+	l := NewMemoryLimiter(30*time.Second, time.Minute)
+	defer l.Close()
+
+	const sessions = 200
+	at := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			ok, err := l.Allow(context.Background(), key, at)
+			if err != nil {
+				t.Error(err)
+			}
+			if !ok {
+				t.Errorf("first submission of %s rejected", key)
+			}
+		}(fmt.Sprintf("session-%d", i))
+	}
+	wg.Wait()
+	if n := l.Len(); n != sessions {
+		t.Errorf("Len = %d, want %d", n, sessions)
+	}
+}
+
+func TestMemoryLimiterEviction(t *testing.T) {
+	l := NewMemoryLimiter(0, 20*time.Millisecond)
+	defer l.Close()
+
+	if _, err := l.Allow(context.Background(), "session", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for l.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle key was not evicted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMemoryLimiterEvictExpired(t *testing.T) {
+	l := NewMemoryLimiter(time.Second, time.Hour)
+	defer l.Close()
+
+	now := time.Now()
+	l.Allow(context.Background(), "idle", now)
+	l.evictExpired(now.Add(30 * time.Minute))
+	if l.Len() != 1 {
+		t.Fatal("key evicted before its TTL")
+	}
+	l.evictExpired(now.Add(2 * time.Hour))
+	if l.Len() != 0 {
+		t.Fatal("key kept after its TTL")
+	}
+}
+
+func TestMemoryLimiterZeroTTL(t *testing.T) {
+	l := NewMemoryLimiter(0, 0)
+	defer l.Close()
+
+	now := time.Now()
+	for _, key := range []string{"a", "b"} {
+		if ok, err := l.Allow(context.Background(), key, now); err != nil || !ok {
+			t.Fatalf("Allow(%s) = %v, %v", key, ok, err)
+		}
+	}
+	l.evictExpired(now.Add(24 * time.Hour))
+	if l.Len() != 2 {
+		t.Errorf("Len = %d, want keys kept without a TTL", l.Len())
+	}
+}
+
+func TestMemoryLimiterRelease(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter(30*time.Second, time.Minute)
+	defer l.Close()
+	ctx := context.Background()
+
+	l.Allow(ctx, "session", base)
+	next := base.Add(40 * time.Second)
+	l.Allow(ctx, "session", next)
+
+	// Only the latest submission can be released
+	l.Release(ctx, "session", base)
+	if ok, _ := l.Allow(ctx, "session", next.Add(time.Second)); ok {
+		t.Fatal("releasing an older submission released the latest")
+	}
+	l.Release(ctx, "session", next)
+	if ok, _ := l.Allow(ctx, "session", next); !ok {
+		t.Fatal("retry of a released submission rejected")
+	}
+}
@@ -0,0 +1,56 @@
+package limiter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PostgresLimiter stores the last accepted submission per key in the
+// submission_limits table. The check and the update are a single upsert, so
+// the interval holds across every location-service replica.
+type PostgresLimiter struct {
+	db       *sql.DB
+	interval time.Duration
+}
+
+func NewPostgresLimiter(db *sql.DB, interval time.Duration) *PostgresLimiter {
+	return &PostgresLimiter{db: db, interval: interval}
+}
+
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, at time.Time) (bool, error) {
+	// The conditional DO UPDATE leaves the row untouched, and returns nothing,
+	// when the previous submission is too recent.
+	var accepted string
+	err := l.db.QueryRowContext(ctx, `INSERT INTO submission_limits (key, last_submission_at) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET last_submission_at = EXCLUDED.last_submission_at, updated_at = CURRENT_TIMESTAMP
+		WHERE submission_limits.last_submission_at <= EXCLUDED.last_submission_at - make_interval(secs => $3)
+		RETURNING key`, key, at.UTC(), l.interval.Seconds()).Scan(&accepted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *PostgresLimiter) Release(ctx context.Context, key string, at time.Time) error {
+	_, err := l.db.ExecContext(ctx, `DELETE FROM submission_limits WHERE key = $1 AND last_submission_at = $2`, key, at.UTC())
+	return err
+}
+
+func (l *PostgresLimiter) Forget(ctx context.Context, key string) error {
+	_, err := l.db.ExecContext(ctx, `DELETE FROM submission_limits WHERE key = $1`, key)
+	return err
+}
+
+// DeleteIdle removes keys that have not been updated for longer than ttl
+func (l *PostgresLimiter) DeleteIdle(ctx context.Context, ttl time.Duration) (int64, error) {
+	res, err := l.db.ExecContext(ctx, `DELETE FROM submission_limits WHERE updated_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`, ttl.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,10 +10,18 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/services/location-service/encryption"
+	"github.com/himanshum9/go-mithril/services/location-service/handlers"
+	"github.com/himanshum9/go-mithril/services/location-service/limiter"
 	"github.com/himanshum9/go-mithril/services/location-service/models"
+	"github.com/himanshum9/go-mithril/services/location-service/outbox"
+	"github.com/himanshum9/go-mithril/services/location-service/retention"
+	"github.com/himanshum9/go-mithril/services/location-service/streaming"
 )
 
 var (
@@ -29,15 +38,94 @@ func main() {
 		log.Fatalf("DB connection failed: %v", err)
 	}
 
+	cfg := config.Load()
+	keyProvider, err := encryption.ProviderFrom(cfg.Encryption)
+	if err != nil {
+		log.Fatalf("Failed to open key provider: %v", err)
+	}
+	models.EnableEncryption(keyProvider, cfg.GetDataKeyCacheTTL())
+
+	if n, err := models.FailInterruptedImportJobs(context.Background()); err != nil {
+		log.Printf("failed to close interrupted import jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("marked %d interrupted import jobs as failed", n)
+	}
+
+	// The cache fills up on ingest as well, so a failed warm-up only leaves
+	// users out until they submit their next point
+	if err := handlers.LoadLatestLocations(context.Background(), cfg.GetLatestLocationsWindow()); err != nil {
+		log.Printf("failed to load latest locations: %v", err)
+	}
+
+	if cfg.Session.SubmissionLimiter == "postgres" {
+		pg := limiter.NewPostgresLimiter(models.DB, cfg.GetSubmissionInterval())
+		handlers.SetSubmissionLimiter(pg)
+		go purgeIdleSubmissionLimits(pg, cfg.GetSessionDuration())
+	}
+	go retention.Run(context.Background(), retention.Config{
+		DefaultDays:   cfg.Retention.DefaultDays,
+		PremakeMonths: cfg.Retention.PremakeMonths,
+		DeleteBatch:   cfg.Retention.DeleteBatch,
+	}, cfg.GetRetentionInterval())
+	go handlers.ForgetErasedUsers(context.Background(), cfg.GetErasurePollInterval())
+	locationStreamer := streaming.NewStreamer(cfg.Kafka.Broker, cfg.Kafka.Topic)
+	handlers.SetLocationStreamer(locationStreamer)
+	go outbox.NewRelay(outbox.ConfigFrom(cfg.Outbox), locationStreamer.StreamLocations).Run(context.Background(), cfg.GetOutboxPollInterval())
+
 	router := gin.Default()
+	// Read by the streaming service, which holds no user token
+	router.GET("/privacy/tenants/:id", ServiceTokenMiddleware(cfg.Privacy.ServiceToken), wrap(handlers.TenantPrivacyPolicies))
 	router.Use(CognitoAuthMiddleware)
-	router.POST("/location", submitLocation)
+	router.POST("/location", wrap(handlers.SubmitLocation))
+	router.POST("/locations/batch", wrap(handlers.SubmitLocationBatch))
+	router.GET("/locations", wrap(handlers.ListLocations))
+	router.GET("/locations/latest", wrap(handlers.GetLatestLocations))
+	router.GET("/locations/nearby", wrap(handlers.NearbyLocations))
+	router.GET("/locations/export", wrap(handlers.ExportLocations))
+	router.POST("/locations/import", wrap(handlers.ImportLocations))
+	router.GET("/locations/import/:id", wrap(handlers.GetImportJob))
+	router.POST("/geofences", wrap(handlers.CreateGeofence))
+	router.GET("/geofences", wrap(handlers.ListGeofences))
+	router.GET("/geofences/events", wrap(handlers.ListGeofenceEvents))
+	router.GET("/geofences/:id", wrap(handlers.GetGeofence))
+	router.PUT("/geofences/:id", wrap(handlers.UpdateGeofence))
+	router.DELETE("/geofences/:id", wrap(handlers.DeleteGeofence))
+	router.GET("/trips", wrap(handlers.ListTrips))
+	router.GET("/stops", wrap(handlers.ListStops))
+	router.GET("/heatmap/:z/:x/:y", wrap(handlers.HeatmapTile))
+	router.GET("/reports/activity", wrap(handlers.ActivityReport))
+	router.GET("/settings/anomaly", wrap(handlers.GetAnomalySettings))
+	router.PUT("/settings/anomaly", wrap(handlers.UpdateAnomalySettings))
+	router.GET("/settings/retention", wrap(handlers.GetRetentionSettings))
+	router.PUT("/settings/retention", wrap(handlers.UpdateRetentionSettings))
+	router.GET("/settings/privacy", wrap(handlers.GetPrivacyPolicy))
+	router.PUT("/settings/privacy", wrap(handlers.UpdatePrivacyPolicy))
+	router.GET("/settings/privacy/users/:user_id", wrap(handlers.GetUserPrivacyPolicy))
+	router.PUT("/settings/privacy/users/:user_id", wrap(handlers.UpdateUserPrivacyPolicy))
+	router.DELETE("/settings/privacy/users/:user_id", wrap(handlers.DeleteUserPrivacyPolicy))
+	router.GET("/users/:user_id/data", wrap(handlers.ExportUserData))
+	router.DELETE("/users/:user_id/data", wrap(handlers.EraseUserData))
+	router.GET("/erasures/:id", wrap(handlers.GetErasure))
+	router.POST("/sessions", wrap(handlers.StartSession))
+	router.GET("/sessions/:id", wrap(handlers.GetSession))
+	router.POST("/sessions/:id/end", wrap(handlers.EndSession))
 
 	if err := router.Run(":8080"); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
 	}
 }
 
+// purgeIdleSubmissionLimits drops limiter rows of sessions that have expired
+func purgeIdleSubmissionLimits(pg *limiter.PostgresLimiter, ttl time.Duration) {
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := pg.DeleteIdle(context.Background(), ttl); err != nil {
+			log.Printf("failed to purge submission limits: %v", err)
+		}
+	}
+}
+
 func CognitoAuthMiddleware(c *gin.Context) {
 	token := c.GetHeader("Authorization")
 	if token == "" {
@@ -51,9 +139,34 @@ func CognitoAuthMiddleware(c *gin.Context) {
 		return
 	}
 	c.Set("claims", claims)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), "claims", claims))
 	c.Next()
 }
 
+// ServiceTokenMiddleware admits other services of the cluster, which send
+// token as their bearer token. Without a token nobody is admitted.
+func ServiceTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// wrap adapts a net/http handler to gin, exposing route parameters through
+// r.PathValue.
+func wrap(h http.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, p := range c.Params {
+			c.Request.SetPathValue(p.Key, p.Value)
+		}
+		h(c.Writer, c.Request)
+	}
+}
+
 func ValidateCognitoJWT(tokenString string) (map[string]interface{}, error) {
 	jwksURL := fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s/.well-known/jwks.json", cognitoRegion, cognitoUserPoolID)
 	resp, err := http.Get(jwksURL)
@@ -93,16 +206,3 @@ func ValidateCognitoJWT(tokenString string) (map[string]interface{}, error) {
 	}
 	return claims, nil
 }
-
-func submitLocation(c *gin.Context) {
-	var l models.Location
-	if err := c.ShouldBindJSON(&l); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	if err := models.SaveLocation(context.Background(), &l); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusCreated, gin.H{"message": "Location submitted successfully", "location": l})
-}
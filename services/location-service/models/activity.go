@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+)
+
+// Granularities of activity rollups
+const (
+	ActivityHourly = "hour"
+	ActivityDaily  = "day"
+)
+
+// ActivityRollup is what a user did during one hour or day: the points
+// stored with a timestamp in the period, the distance and active time
+// since the previous point of each, and where and when the user was seen
+type ActivityRollup struct {
+	TenantID       string       `json:"tenant_id"`
+	UserID         string       `json:"user_id"`
+	Granularity    string       `json:"granularity"`
+	PeriodStart    time.Time    `json:"period_start"`
+	PointCount     int64        `json:"point_count"`
+	DistanceMeters float64      `json:"distance_m"`
+	ActiveSecs     float64      `json:"active_s"`
+	BBox           ActivityBBox `json:"bbox"`
+	FirstSeen      time.Time    `json:"first_seen"`
+	LastSeen       time.Time    `json:"last_seen"`
+	// Position at LastSeen, where the next period's distance starts
+	Last Coordinate `json:"-"`
+}
+
+// ActivityBBox bounds the positions of a rollup
+type ActivityBBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// ActivityPeriodStart returns the start of the hour or day of t as stored
+// in TIMESTAMP columns, which keep the wall clock of t
+func ActivityPeriodStart(granularity string, t time.Time) time.Time {
+	if granularity == ActivityDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// ActivityQuery filters the rollups of a tenant by period start. Results
+// are ordered by period start, then user ID.
+type ActivityQuery struct {
+	pagination.Params
+	TenantID    string
+	UserID      string
+	Granularity string
+	From        *time.Time
+	To          *time.Time
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+)
+
+const activityColumns = `tenant_id, user_id, period_start, point_count, distance_m, active_seconds,
+	min_latitude, min_longitude, max_latitude, max_longitude, first_seen, last_seen, last_latitude, last_longitude`
+
+// activityInsertBatch keeps rollup upserts below the parameter limit of a
+// statement
+const activityInsertBatch = 1000
+
+func activityTable(granularity string) string {
+	if granularity == ActivityDaily {
+		return "activity_daily"
+	}
+	return "activity_hourly"
+}
+
+// LastActivityPoint returns the time and position of the latest point
+// rolled up for a tenant user, or nil when there is none
+func LastActivityPoint(ctx context.Context, tx *sql.Tx, tenantID, userID string) (*ActivityRollup, error) {
+	r := &ActivityRollup{TenantID: tenantID, UserID: userID, Granularity: ActivityHourly}
+	err := tx.QueryRowContext(ctx, `SELECT period_start, last_seen, last_latitude, last_longitude FROM activity_hourly
+		WHERE tenant_id = $1 AND user_id = $2 ORDER BY period_start DESC LIMIT 1`, tenantID, userID).
+		Scan(&r.PeriodStart, &r.LastSeen, &r.Last.Latitude, &r.Last.Longitude)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// AddActivityRollups merges rollups into the stored ones of the same user
+// and period
+func AddActivityRollups(ctx context.Context, tx *sql.Tx, rollups []*ActivityRollup) error {
+	byTable := make(map[string][]*ActivityRollup)
+	for _, r := range rollups {
+		byTable[activityTable(r.Granularity)] = append(byTable[activityTable(r.Granularity)], r)
+	}
+	for table, all := range byTable {
+		for len(all) > 0 {
+			rs := all[:min(len(all), activityInsertBatch)]
+			all = all[len(rs):]
+			if err := upsertActivityRollups(ctx, tx, table, rs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func upsertActivityRollups(ctx context.Context, tx *sql.Tx, table string, rs []*ActivityRollup) error {
+	placeholders := make([]string, len(rs))
+	args := make([]interface{}, 0, 14*len(rs))
+	for i, r := range rs {
+		n := len(args)
+		ph := make([]string, 14)
+		for j := range ph {
+			ph[j] = fmt.Sprintf("$%d", n+j+1)
+		}
+		placeholders[i] = "(" + strings.Join(ph, ", ") + ")"
+		args = append(args, r.TenantID, r.UserID, r.PeriodStart, r.PointCount, r.DistanceMeters, r.ActiveSecs,
+			r.BBox.MinLat, r.BBox.MinLon, r.BBox.MaxLat, r.BBox.MaxLon, r.FirstSeen, r.LastSeen, r.Last.Latitude, r.Last.Longitude)
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s (`+activityColumns+`) VALUES `+strings.Join(placeholders, ", ")+`
+		ON CONFLICT (tenant_id, user_id, period_start) DO UPDATE SET
+			point_count = %[1]s.point_count + EXCLUDED.point_count,
+			distance_m = %[1]s.distance_m + EXCLUDED.distance_m,
+			active_seconds = %[1]s.active_seconds + EXCLUDED.active_seconds,
+			min_latitude = LEAST(%[1]s.min_latitude, EXCLUDED.min_latitude),
+			min_longitude = LEAST(%[1]s.min_longitude, EXCLUDED.min_longitude),
+			max_latitude = GREATEST(%[1]s.max_latitude, EXCLUDED.max_latitude),
+			max_longitude = GREATEST(%[1]s.max_longitude, EXCLUDED.max_longitude),
+			first_seen = LEAST(%[1]s.first_seen, EXCLUDED.first_seen),
+			last_seen = GREATEST(%[1]s.last_seen, EXCLUDED.last_seen),
+			last_latitude = CASE WHEN EXCLUDED.last_seen >= %[1]s.last_seen THEN EXCLUDED.last_latitude ELSE %[1]s.last_latitude END,
+			last_longitude = CASE WHEN EXCLUDED.last_seen >= %[1]s.last_seen THEN EXCLUDED.last_longitude ELSE %[1]s.last_longitude END`,
+		table), args...)
+	return err
+}
+
+// DeleteActivityRollups removes the hourly and daily rollups of a tenant
+// user for the periods starting in [from, to)
+func DeleteActivityRollups(ctx context.Context, tx *sql.Tx, tenantID, userID string, from, to time.Time) error {
+	for _, table := range []string{"activity_hourly", "activity_daily"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE tenant_id = $1 AND user_id = $2 AND period_start >= $3 AND period_start < $4`,
+			tenantID, userID, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryActivity returns one page of the rollups matching q
+func QueryActivity(ctx context.Context, q ActivityQuery) (*pagination.Page[ActivityRollup], error) {
+	if q.Limit <= 0 {
+		q.Limit = pagination.DefaultLimit
+	}
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{q.TenantID}
+	add := func(cond string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+	if q.UserID != "" {
+		add("user_id = $%d", q.UserID)
+	}
+	if q.From != nil {
+		add("period_start >= $%d", *q.From)
+	}
+	if q.To != nil {
+		add("period_start < $%d", *q.To)
+	}
+	if q.Cursor != nil {
+		ts, err := time.Parse(time.RFC3339Nano, q.Cursor.Value)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		args = append(args, ts, q.Cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(period_start, user_id) %s ($%d, $%d)", q.Comparator(), len(args)-1, len(args)))
+	}
+	args = append(args, q.Limit+1)
+	rows, err := DB.QueryContext(ctx, fmt.Sprintf(`SELECT `+activityColumns+` FROM %s WHERE %s
+		ORDER BY period_start %s, user_id %s LIMIT $%d`,
+		activityTable(q.Granularity), strings.Join(conditions, " AND "), q.Direction(), q.Direction(), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rollups := []ActivityRollup{}
+	for rows.Next() {
+		r := ActivityRollup{Granularity: q.Granularity}
+		if err := rows.Scan(&r.TenantID, &r.UserID, &r.PeriodStart, &r.PointCount, &r.DistanceMeters, &r.ActiveSecs,
+			&r.BBox.MinLat, &r.BBox.MinLon, &r.BBox.MaxLat, &r.BBox.MaxLon, &r.FirstSeen, &r.LastSeen, &r.Last.Latitude, &r.Last.Longitude); err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &pagination.Page[ActivityRollup]{Items: rollups}
+	if len(rollups) > q.Limit {
+		page.Items = rollups[:q.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = pagination.EncodeCursor(pagination.Cursor{
+			Value: last.PeriodStart.Format(time.RFC3339Nano),
+			ID:    last.UserID,
+		})
+	}
+	return page, nil
+}
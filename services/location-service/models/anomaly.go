@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/validation"
+)
+
+// How a tenant handles points whose risk score reaches its reject score
+const (
+	AnomalyModeFlag   = "flag"
+	AnomalyModeReject = "reject"
+)
+
+// AnomalyAlertType is the event type of published anomaly alerts
+const AnomalyAlertType = "location.anomaly"
+
+// Actions taken on a point that raised an alert
+const (
+	AnomalyActionFlagged  = "flagged"
+	AnomalyActionRejected = "rejected"
+)
+
+// AnomalySettings is the anomaly handling chosen by a tenant. In flag mode
+// points are stored with their risk score; in reject mode points scoring
+// at least RejectScore are refused.
+type AnomalySettings struct {
+	TenantID    string    `json:"tenant_id"`
+	Mode        string    `json:"mode"`
+	RejectScore float64   `json:"reject_score"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+func (s *AnomalySettings) Validate(v *validation.Validator) {
+	if v.Required("mode", s.Mode != "") {
+		v.OneOf("mode", s.Mode, AnomalyModeFlag, AnomalyModeReject)
+	}
+	v.Range("reject_score", &s.RejectScore, 0, 1)
+}
+
+// Rejects reports whether a point with the given risk score is refused
+func (s *AnomalySettings) Rejects(score float64) bool {
+	return s.Mode == AnomalyModeReject && score > 0 && score >= s.RejectScore
+}
+
+// AnomalyAlert reports a suspicious point. LocationID is unset for rejected
+// points, which are not stored.
+type AnomalyAlert struct {
+	TenantID   string    `json:"tenant_id"`
+	UserID     string    `json:"user_id"`
+	SessionID  string    `json:"session_id,omitempty"`
+	LocationID int64     `json:"location_id,omitempty"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Timestamp  time.Time `json:"timestamp"`
+	RiskScore  float64   `json:"risk_score"`
+	Anomalies  []string  `json:"anomalies"`
+	Action     string    `json:"action"`
+}
+
+// NewAnomalyAlert describes l, which has been scored
+func NewAnomalyAlert(l *Location, action string) AnomalyAlert {
+	a := AnomalyAlert{
+		TenantID:   l.TenantID,
+		UserID:     l.UserID,
+		SessionID:  l.SessionID,
+		LocationID: l.ID,
+		Latitude:   l.Latitude,
+		Longitude:  l.Longitude,
+		Timestamp:  l.Timestamp,
+		Anomalies:  l.Anomalies,
+		Action:     action,
+	}
+	if l.RiskScore != nil {
+		a.RiskScore = *l.RiskScore
+	}
+	return a
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrAnomalySettingsNotFound = errors.New("anomaly settings not found")
+
+// GetAnomalySettings returns the anomaly handling of a tenant, or
+// ErrAnomalySettingsNotFound if the tenant never chose one
+func GetAnomalySettings(ctx context.Context, tenantID string) (*AnomalySettings, error) {
+	s := &AnomalySettings{TenantID: tenantID}
+	err := DB.QueryRowContext(ctx, `SELECT mode, reject_score, updated_at FROM anomaly_settings WHERE tenant_id = $1`, tenantID).
+		Scan(&s.Mode, &s.RejectScore, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAnomalySettingsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SaveAnomalySettings creates or replaces the anomaly handling of a tenant
+func SaveAnomalySettings(ctx context.Context, s *AnomalySettings) error {
+	s.UpdatedAt = time.Now()
+	_, err := DB.ExecContext(ctx, `INSERT INTO anomaly_settings (tenant_id, mode, reject_score, updated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id) DO UPDATE SET mode = EXCLUDED.mode, reject_score = EXCLUDED.reject_score, updated_at = EXCLUDED.updated_at`,
+		s.TenantID, s.Mode, s.RejectScore, s.UpdatedAt)
+	return err
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 
 	_ "github.com/lib/pq"
@@ -14,5 +15,8 @@ func InitDB(connStr string) error {
 	if err != nil {
 		return err
 	}
-	return DB.Ping()
+	if err := DB.Ping(); err != nil {
+		return err
+	}
+	return detectPostGIS(context.Background())
 }
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/encryption"
+)
+
+// ErrEncryptionDisabled is returned when a sealed location is read while
+// no key provider is configured
+var ErrEncryptionDisabled = errors.New("location is encrypted but no key provider is configured")
+
+// keyring holds the data keys of tenants; nil while encryption is off
+var keyring *Keyring
+
+// EnableEncryption seals the coordinates and metadata of locations stored
+// from now on with data keys wrapped by provider. The current data key of a
+// tenant is looked up again after currentTTL, so a rotation reaches every
+// replica within it. A nil provider turns encryption off; sealed locations
+// can then no longer be read.
+func EnableEncryption(provider encryption.KeyProvider, currentTTL time.Duration) {
+	if provider == nil {
+		keyring = nil
+		return
+	}
+	keyring = &Keyring{
+		provider:   provider,
+		currentTTL: currentTTL,
+		keys:       make(map[dataKeyID][]byte),
+		current:    make(map[string]currentDataKey),
+	}
+}
+
+// EncryptionEnabled reports whether new locations are sealed
+func EncryptionEnabled() bool {
+	return keyring != nil
+}
+
+type dataKeyID struct {
+	tenantID string
+	version  int
+}
+
+type currentDataKey struct {
+	version   int
+	fetchedAt time.Time
+}
+
+// Keyring unwraps data keys through the key provider and caches them.
+// Unwrapped keys never change for a version and are kept for the life of
+// the process.
+type Keyring struct {
+	provider   encryption.KeyProvider
+	currentTTL time.Duration
+
+	mu      sync.Mutex
+	keys    map[dataKeyID][]byte
+	current map[string]currentDataKey
+}
+
+// currentKey returns the newest data key of a tenant, creating the first
+// one when the tenant has none
+func (k *Keyring) currentKey(ctx context.Context, tenantID string) (int, []byte, error) {
+	k.mu.Lock()
+	c, ok := k.current[tenantID]
+	k.mu.Unlock()
+	if !ok || time.Since(c.fetchedAt) > k.currentTTL {
+		version, err := latestDataKeyVersion(ctx, tenantID)
+		if err != nil {
+			return 0, nil, err
+		}
+		if version == 0 {
+			if version, err = k.createDataKey(ctx, tenantID, 1); err != nil {
+				return 0, nil, err
+			}
+		}
+		c = currentDataKey{version: version, fetchedAt: time.Now()}
+		k.mu.Lock()
+		k.current[tenantID] = c
+		k.mu.Unlock()
+	}
+	key, err := k.key(ctx, tenantID, c.version)
+	return c.version, key, err
+}
+
+// key returns a data key of a tenant by version
+func (k *Keyring) key(ctx context.Context, tenantID string, version int) ([]byte, error) {
+	id := dataKeyID{tenantID, version}
+	k.mu.Lock()
+	key, ok := k.keys[id]
+	k.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	var wrapped []byte
+	var masterKeyID string
+	err := DB.QueryRowContext(ctx, `SELECT wrapped_key, master_key_id FROM tenant_data_keys WHERE tenant_id = $1 AND version = $2`,
+		tenantID, version).Scan(&wrapped, &masterKeyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("data key %d of tenant %s not found", version, tenantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key, err = k.provider.Unwrap(ctx, masterKeyID, wrapped); err != nil {
+		return nil, fmt.Errorf("unwrap data key %d of tenant %s: %w", version, tenantID, err)
+	}
+	k.mu.Lock()
+	k.keys[id] = key
+	k.mu.Unlock()
+	return key, nil
+}
+
+// createDataKey stores a new data key of a tenant as the given version, and
+// returns the newest version, which is another one when a concurrent
+// writer created it first
+func (k *Keyring) createDataKey(ctx context.Context, tenantID string, version int) (int, error) {
+	key, err := encryption.NewDataKey()
+	if err != nil {
+		return 0, err
+	}
+	wrapped, masterKeyID, err := k.provider.Wrap(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := DB.ExecContext(ctx, `INSERT INTO tenant_data_keys (tenant_id, version, wrapped_key, master_key_id, created_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (tenant_id, version) DO NOTHING`,
+		tenantID, version, wrapped, masterKeyID, time.Now()); err != nil {
+		return 0, err
+	}
+	k.forgetCurrent(tenantID)
+	return latestDataKeyVersion(ctx, tenantID)
+}
+
+func (k *Keyring) forgetCurrent(tenantID string) {
+	k.mu.Lock()
+	delete(k.current, tenantID)
+	k.mu.Unlock()
+}
+
+func latestDataKeyVersion(ctx context.Context, tenantID string) (int, error) {
+	var version int
+	err := DB.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM tenant_data_keys WHERE tenant_id = $1`, tenantID).Scan(&version)
+	return version, err
+}
+
+// sealedFields are the columns of a location that are stored encrypted
+type sealedFields struct {
+	Latitude  float64           `json:"lat"`
+	Longitude float64           `json:"lon"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// sealLocation encrypts the coordinates and metadata of l with the current
+// data key of its tenant, and returns the key version and ciphertext. The
+// ciphertext is bound to the tenant so it cannot be moved to another one.
+func sealLocation(ctx context.Context, l *Location) (int, []byte, error) {
+	version, key, err := keyring.currentKey(ctx, l.TenantID)
+	if err != nil {
+		return 0, nil, err
+	}
+	plaintext, err := json.Marshal(sealedFields{Latitude: l.Latitude, Longitude: l.Longitude, Metadata: l.Metadata})
+	if err != nil {
+		return 0, nil, err
+	}
+	ciphertext, err := encryption.Seal(key, plaintext, []byte(l.TenantID))
+	return version, ciphertext, err
+}
+
+// openLocation decrypts the sealed columns of l
+func openLocation(ctx context.Context, l *Location, version int, ciphertext []byte) error {
+	if keyring == nil {
+		return ErrEncryptionDisabled
+	}
+	key, err := keyring.key(ctx, l.TenantID, version)
+	if err != nil {
+		return err
+	}
+	plaintext, err := encryption.Open(key, ciphertext, []byte(l.TenantID))
+	if err != nil {
+		return fmt.Errorf("decrypt location %d: %w", l.ID, err)
+	}
+	var f sealedFields
+	if err := json.Unmarshal(plaintext, &f); err != nil {
+		return err
+	}
+	l.Latitude, l.Longitude, l.Metadata = f.Latitude, f.Longitude, f.Metadata
+	return nil
+}
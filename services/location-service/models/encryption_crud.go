@@ -0,0 +1,186 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var errNoKeyProvider = errors.New("no key provider is configured")
+
+// RotateDataKey creates a new data key version for a tenant, used for the
+// locations stored from then on, and returns it. Older versions are kept
+// for the locations still sealed with them.
+func RotateDataKey(ctx context.Context, tenantID string) (int, error) {
+	if keyring == nil {
+		return 0, errNoKeyProvider
+	}
+	version, err := latestDataKeyVersion(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return keyring.createDataKey(ctx, tenantID, version+1)
+}
+
+// RewrapDataKeys wraps every data key not wrapped with the current master
+// key again with it, so older master keys can be retired, and returns the
+// number of keys rewrapped. Locations are not touched.
+func RewrapDataKeys(ctx context.Context) (int, error) {
+	if keyring == nil {
+		return 0, errNoKeyProvider
+	}
+	current := keyring.provider.CurrentKeyID()
+	rows, err := DB.QueryContext(ctx, `SELECT tenant_id, version, wrapped_key, master_key_id FROM tenant_data_keys
+		WHERE master_key_id <> $1`, current)
+	if err != nil {
+		return 0, err
+	}
+	type wrappedKey struct {
+		tenantID    string
+		version     int
+		wrapped     []byte
+		masterKeyID string
+	}
+	var keys []wrappedKey
+	for rows.Next() {
+		var k wrappedKey
+		if err := rows.Scan(&k.tenantID, &k.version, &k.wrapped, &k.masterKeyID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, k := range keys {
+		key, err := keyring.provider.Unwrap(ctx, k.masterKeyID, k.wrapped)
+		if err != nil {
+			return i, err
+		}
+		wrapped, masterKeyID, err := keyring.provider.Wrap(ctx, key)
+		if err != nil {
+			return i, err
+		}
+		if _, err := DB.ExecContext(ctx, `UPDATE tenant_data_keys SET wrapped_key = $3, master_key_id = $4
+			WHERE tenant_id = $1 AND version = $2 AND master_key_id = $5`,
+			k.tenantID, k.version, wrapped, masterKeyID, k.masterKeyID); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
+
+// ListLocationTenants returns the tenants that have stored locations. It
+// reads every partition and is meant for maintenance tools.
+func ListLocationTenants(ctx context.Context) ([]string, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT DISTINCT tenant_id FROM locations ORDER BY tenant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tenants []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, id)
+	}
+	return tenants, rows.Err()
+}
+
+// ReencryptLocations seals every location of a tenant with its current
+// data key, including locations stored in plain text, or with decrypt
+// stores them all in plain text again. Locations are rewritten batchSize at
+// a time, each batch in its own transaction, and progress is called with
+// the running total. It returns the number of locations rewritten.
+func ReencryptLocations(ctx context.Context, tenantID string, decrypt bool, batchSize int, progress func(n int64)) (int64, error) {
+	if keyring == nil {
+		return 0, errNoKeyProvider
+	}
+	var target sql.NullInt64
+	if !decrypt {
+		version, _, err := keyring.currentKey(ctx, tenantID)
+		if err != nil {
+			return 0, err
+		}
+		target = sql.NullInt64{Int64: int64(version), Valid: true}
+	}
+	setGeog := ""
+	if PostGIS {
+		setGeog = ", geog = " + pointGeography("$4", "$3")
+	}
+
+	var total int64
+	afterTime, afterID := time.Time{}, int64(0)
+	for {
+		n, last, err := reencryptBatch(ctx, tenantID, target, setGeog, afterTime, afterID, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if n == 0 {
+			return total, nil
+		}
+		total += int64(n)
+		afterTime, afterID = last.Timestamp, last.ID
+		if progress != nil {
+			progress(total)
+		}
+	}
+}
+
+// reencryptBatch rewrites the next batch of locations after (afterTime,
+// afterID) not yet stored under target, and returns their number and the
+// last of them
+func reencryptBatch(ctx context.Context, tenantID string, target sql.NullInt64, setGeog string, afterTime time.Time, afterID int64, batchSize int) (int, *Location, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+locationSelectColumns+` FROM locations
+		WHERE tenant_id = $1 AND key_version IS DISTINCT FROM $2 AND (timestamp, id) > ($3, $4)
+		ORDER BY timestamp, id LIMIT $5 FOR UPDATE`, tenantID, target, afterTime, afterID, batchSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	var batch []Location
+	for rows.Next() {
+		l, err := scanLocation(ctx, rows)
+		if err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		batch = append(batch, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	if len(batch) == 0 {
+		return 0, nil, nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE locations SET latitude = $3, longitude = $4, metadata = $5, key_version = $6, ciphertext = $7`+setGeog+`
+		WHERE id = $1 AND timestamp = $2`)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer stmt.Close()
+	for i := range batch {
+		l := &batch[i]
+		latitude, longitude, metadata, keyVersion, ciphertext, err := storedColumns(ctx, l, target.Valid)
+		if err != nil {
+			return 0, nil, err
+		}
+		if _, err := stmt.ExecContext(ctx, l.ID, l.Timestamp, latitude, longitude, metadata, keyVersion, ciphertext); err != nil {
+			return 0, nil, err
+		}
+	}
+	return len(batch), &batch[len(batch)-1], tx.Commit()
+}
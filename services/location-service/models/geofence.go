@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+)
+
+const (
+	GeofenceTypeCircle  = "circle"
+	GeofenceTypePolygon = "polygon"
+)
+
+// Accepted geofence sizes
+const (
+	MinGeofenceRadiusMeters = 1.0
+	MaxGeofenceRadiusMeters = 100000.0
+	MaxGeofenceVertices     = 1000
+	MaxGeofenceNameLen      = 255
+)
+
+// Coordinate is a position in degrees
+type Coordinate struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Geofence is a tenant-defined area, either a circle around Center or a
+// polygon. Users inside it for DwellSeconds trigger a dwell event; zero
+// disables dwell events.
+type Geofence struct {
+	ID           string       `json:"id"`
+	TenantID     string       `json:"tenant_id"`
+	Name         string       `json:"name"`
+	Type         string       `json:"type"`
+	Center       *Coordinate  `json:"center,omitempty"`
+	RadiusMeters float64      `json:"radius_m,omitempty"`
+	Polygon      []Coordinate `json:"polygon,omitempty"`
+	DwellSeconds int          `json:"dwell_seconds"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// Validate records the problems of a geofence definition in v
+func (g *Geofence) Validate(v *validation.Validator) {
+	if v.Required("name", g.Name != "") {
+		v.MaxLength("name", g.Name, MaxGeofenceNameLen)
+	}
+	if !v.Required("type", g.Type != "") {
+		return
+	}
+	v.OneOf("type", g.Type, GeofenceTypeCircle, GeofenceTypePolygon)
+	if g.DwellSeconds < 0 {
+		v.Add("dwell_seconds", apierror.FieldOutOfRange, "dwell_seconds must not be negative")
+	}
+	switch g.Type {
+	case GeofenceTypeCircle:
+		if v.Required("center", g.Center != nil) {
+			v.Latitude("center.latitude", &g.Center.Latitude)
+			v.Longitude("center.longitude", &g.Center.Longitude)
+		}
+		v.Range("radius_m", &g.RadiusMeters, MinGeofenceRadiusMeters, MaxGeofenceRadiusMeters)
+	case GeofenceTypePolygon:
+		if len(g.Polygon) < 3 || len(g.Polygon) > MaxGeofenceVertices {
+			v.Add("polygon", apierror.FieldInvalid, fmt.Sprintf("polygon must have between 3 and %d vertices", MaxGeofenceVertices))
+			return
+		}
+		for i := range g.Polygon {
+			v.Latitude(fmt.Sprintf("polygon[%d].latitude", i), &g.Polygon[i].Latitude)
+			v.Longitude(fmt.Sprintf("polygon[%d].longitude", i), &g.Polygon[i].Longitude)
+		}
+	}
+}
+
+// Contains reports whether the point lies inside the geofence
+func (g *Geofence) Contains(lat, lon float64) bool {
+	p := geometry.Point{Lat: lat, Lon: lon}
+	switch g.Type {
+	case GeofenceTypeCircle:
+		return g.Center != nil && geometry.Haversine(p, geometry.Point{Lat: g.Center.Latitude, Lon: g.Center.Longitude}) <= g.RadiusMeters
+	case GeofenceTypePolygon:
+		ring := make([]geometry.Point, len(g.Polygon))
+		for i, c := range g.Polygon {
+			ring[i] = geometry.Point{Lat: c.Latitude, Lon: c.Longitude}
+		}
+		return geometry.InPolygon(p, ring)
+	}
+	return false
+}
+
+// Bounds returns a box enclosing the geofence. Circles reaching a pole or
+// the antimeridian get the full longitude range.
+func (g *Geofence) Bounds() BoundingBox {
+	if g.Type == GeofenceTypeCircle && g.Center != nil {
+		dLat := geometry.MetersToDegreesLat(g.RadiusMeters)
+		b := BoundingBox{
+			MinLat: math.Max(-90, g.Center.Latitude-dLat),
+			MaxLat: math.Min(90, g.Center.Latitude+dLat),
+			MinLon: -180,
+			MaxLon: 180,
+		}
+		dLon := geometry.MetersToDegreesLon(g.RadiusMeters, math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat)))
+		if g.Center.Longitude-dLon >= -180 && g.Center.Longitude+dLon <= 180 && b.MinLat > -90 && b.MaxLat < 90 {
+			b.MinLon = g.Center.Longitude - dLon
+			b.MaxLon = g.Center.Longitude + dLon
+		}
+		return b
+	}
+	b := BoundingBox{MinLat: 90, MinLon: 180, MaxLat: -90, MaxLon: -180}
+	for _, c := range g.Polygon {
+		b.MinLat = math.Min(b.MinLat, c.Latitude)
+		b.MaxLat = math.Max(b.MaxLat, c.Latitude)
+		b.MinLon = math.Min(b.MinLon, c.Longitude)
+		b.MaxLon = math.Max(b.MaxLon, c.Longitude)
+	}
+	return b
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/lib/pq"
+)
+
+var ErrGeofenceNotFound = errors.New("geofence not found")
+
+const geofenceSelectColumns = `id, tenant_id, name, type, center_latitude, center_longitude, COALESCE(radius_m, 0), polygon, dwell_seconds, created_at, updated_at`
+
+// geofenceArgs returns the shape and bounds of g as column arguments
+func geofenceArgs(g *Geofence) ([]interface{}, error) {
+	var centerLat, centerLon, radius, polygon interface{}
+	if g.Center != nil {
+		centerLat, centerLon, radius = g.Center.Latitude, g.Center.Longitude, g.RadiusMeters
+	}
+	if len(g.Polygon) > 0 {
+		b, err := json.Marshal(g.Polygon)
+		if err != nil {
+			return nil, err
+		}
+		polygon = string(b)
+	}
+	bounds := g.Bounds()
+	return []interface{}{centerLat, centerLon, radius, polygon, bounds.MinLat, bounds.MinLon, bounds.MaxLat, bounds.MaxLon}, nil
+}
+
+func scanGeofence(row rowScanner) (Geofence, error) {
+	var g Geofence
+	var centerLat, centerLon sql.NullFloat64
+	var polygon []byte
+	if err := row.Scan(&g.ID, &g.TenantID, &g.Name, &g.Type, &centerLat, &centerLon, &g.RadiusMeters, &polygon, &g.DwellSeconds, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return g, err
+	}
+	if centerLat.Valid && centerLon.Valid {
+		g.Center = &Coordinate{Latitude: centerLat.Float64, Longitude: centerLon.Float64}
+	}
+	if len(polygon) > 0 {
+		if err := json.Unmarshal(polygon, &g.Polygon); err != nil {
+			return g, err
+		}
+	}
+	return g, nil
+}
+
+func SaveGeofence(ctx context.Context, g *Geofence) error {
+	shape, err := geofenceArgs(g)
+	if err != nil {
+		return err
+	}
+	g.ID = newID()
+	g.CreatedAt = time.Now()
+	g.UpdatedAt = g.CreatedAt
+	args := append([]interface{}{g.ID, g.TenantID, g.Name, g.Type, g.DwellSeconds, g.CreatedAt}, shape...)
+	geogColumn, geogValue := "", ""
+	if PostGIS {
+		args = append(args, geofenceWKT(g))
+		geogColumn, geogValue = ", geog", ", "+shapeGeography("$15")
+	}
+	_, err = DB.ExecContext(ctx, `INSERT INTO geofences (id, tenant_id, name, type, dwell_seconds, created_at, updated_at,
+		center_latitude, center_longitude, radius_m, polygon, min_latitude, min_longitude, max_latitude, max_longitude`+geogColumn+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8, $9, $10, $11, $12, $13, $14`+geogValue+`)`, args...)
+	return err
+}
+
+// UpdateGeofence replaces the definition of an existing geofence. Users
+// inside it keep their state, so a reshaped fence reports exits on their
+// next point.
+func UpdateGeofence(ctx context.Context, g *Geofence) error {
+	shape, err := geofenceArgs(g)
+	if err != nil {
+		return err
+	}
+	g.UpdatedAt = time.Now()
+	args := append([]interface{}{g.ID, g.TenantID, g.Name, g.Type, g.DwellSeconds, g.UpdatedAt}, shape...)
+	setGeog := ""
+	if PostGIS {
+		args = append(args, geofenceWKT(g))
+		setGeog = ", geog = " + shapeGeography("$15")
+	}
+	err = DB.QueryRowContext(ctx, `UPDATE geofences SET name = $3, type = $4, dwell_seconds = $5, updated_at = $6,
+		center_latitude = $7, center_longitude = $8, radius_m = $9, polygon = $10,
+		min_latitude = $11, min_longitude = $12, max_latitude = $13, max_longitude = $14`+setGeog+`
+		WHERE id = $1 AND tenant_id = $2 RETURNING created_at`, args...).Scan(&g.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrGeofenceNotFound
+	}
+	return err
+}
+
+// GetGeofence returns a geofence belonging to tenantID
+func GetGeofence(ctx context.Context, tenantID, id string) (*Geofence, error) {
+	g, err := scanGeofence(DB.QueryRowContext(ctx, `SELECT `+geofenceSelectColumns+` FROM geofences WHERE id = $1 AND tenant_id = $2`, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGeofenceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// DeleteGeofence removes a geofence and the states of users inside it. Its
+// events are kept.
+func DeleteGeofence(ctx context.Context, tenantID, id string) error {
+	res, err := DB.ExecContext(ctx, `DELETE FROM geofences WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrGeofenceNotFound
+	}
+	return err
+}
+
+// ListGeofences returns one page of a tenant's geofences ordered by name
+func ListGeofences(ctx context.Context, tenantID string, p pagination.Params) (*pagination.Page[Geofence], error) {
+	if p.Limit <= 0 {
+		p.Limit = pagination.DefaultLimit
+	}
+	args := []interface{}{tenantID}
+	where := "tenant_id = $1"
+	if p.Cursor != nil {
+		args = append(args, p.Cursor.Value, p.Cursor.ID)
+		where += fmt.Sprintf(" AND (name, id) %s ($2, $3)", p.Comparator())
+	}
+	args = append(args, p.Limit+1)
+	rows, err := DB.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM geofences WHERE %s ORDER BY name %s, id %s LIMIT $%d`,
+		geofenceSelectColumns, where, p.Direction(), p.Direction(), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	geofences := []Geofence{}
+	for rows.Next() {
+		g, err := scanGeofence(rows)
+		if err != nil {
+			return nil, err
+		}
+		geofences = append(geofences, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &pagination.Page[Geofence]{Items: geofences}
+	if len(geofences) > p.Limit {
+		page.Items = geofences[:p.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = pagination.EncodeCursor(pagination.Cursor{Value: last.Name, ID: last.ID})
+	}
+	return page, nil
+}
+
+// candidateGeofences returns the tenant's geofences that may contain one of
+// points, plus those listed in ids. With PostGIS the shapes are matched
+// against the points themselves, otherwise bounds are matched against the
+// box around the points.
+func candidateGeofences(ctx context.Context, tx *sql.Tx, tenantID string, points []*Location, ids []string) ([]Geofence, error) {
+	var rows *sql.Rows
+	var err error
+	if PostGIS {
+		lats := make([]float64, len(points))
+		lons := make([]float64, len(points))
+		for i, l := range points {
+			lats[i], lons[i] = l.Latitude, l.Longitude
+		}
+		// A meter of slack keeps fences whose edge passes through a point;
+		// Contains makes the final decision
+		rows, err = tx.QueryContext(ctx, `SELECT `+geofenceSelectColumns+` FROM geofences WHERE tenant_id = $1 AND (id = ANY($2)
+			OR ST_DWithin(geog, (SELECT ST_Collect(ST_MakePoint(lon, lat))::geography FROM unnest($3::DOUBLE PRECISION[], $4::DOUBLE PRECISION[]) AS p(lat, lon)),
+				COALESCE(radius_m, 0) + 1, false))`,
+			tenantID, pq.Array(ids), pq.Array(lats), pq.Array(lons))
+	} else {
+		b := BoundingBox{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+		for _, l := range points {
+			b.MinLat, b.MaxLat = math.Min(b.MinLat, l.Latitude), math.Max(b.MaxLat, l.Latitude)
+			b.MinLon, b.MaxLon = math.Min(b.MinLon, l.Longitude), math.Max(b.MaxLon, l.Longitude)
+		}
+		rows, err = tx.QueryContext(ctx, `SELECT `+geofenceSelectColumns+` FROM geofences WHERE tenant_id = $1 AND (id = ANY($2)
+			OR (min_latitude <= $5 AND max_latitude >= $3 AND min_longitude <= $6 AND max_longitude >= $4))`,
+			tenantID, pq.Array(ids), b.MinLat, b.MinLon, b.MaxLat, b.MaxLon)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var geofences []Geofence
+	for rows.Next() {
+		g, err := scanGeofence(rows)
+		if err != nil {
+			return nil, err
+		}
+		geofences = append(geofences, g)
+	}
+	return geofences, rows.Err()
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	GeofenceEventEnter = "geofence.enter"
+	GeofenceEventExit  = "geofence.exit"
+	GeofenceEventDwell = "geofence.dwell"
+)
+
+// GeofenceEvent records a user entering, leaving or dwelling in a geofence.
+// The position and time are those of the point that caused it.
+type GeofenceEvent struct {
+	ID         int64     `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	UserID     string    `json:"user_id"`
+	GeofenceID string    `json:"geofence_id"`
+	Type       string    `json:"type"`
+	LocationID int64     `json:"location_id,omitempty"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// GeofenceState records that a user is inside a geofence
+type GeofenceState struct {
+	GeofenceID    string
+	EnteredAt     time.Time
+	DwellNotified bool
+}
+
+// geofenceTransition returns the event caused by l in g and the user's next
+// state in g, given the current one; a nil state means outside. Dwell is
+// only noticed when a point arrives, so its event may come late.
+func geofenceTransition(g *Geofence, state *GeofenceState, l *Location) (string, *GeofenceState) {
+	inside := g.Contains(l.Latitude, l.Longitude)
+	switch {
+	case inside && state == nil:
+		return GeofenceEventEnter, &GeofenceState{GeofenceID: g.ID, EnteredAt: l.Timestamp}
+	case !inside && state != nil:
+		return GeofenceEventExit, nil
+	case inside && g.DwellSeconds > 0 && !state.DwellNotified &&
+		l.Timestamp.Sub(state.EnteredAt) >= time.Duration(g.DwellSeconds)*time.Second:
+		next := *state
+		next.DwellNotified = true
+		return GeofenceEventDwell, &next
+	}
+	return "", state
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+)
+
+// EvaluateGeofences moves a tenant user through the geofences of the tenant
+// point by point, in timestamp order, and stores the resulting states and
+// events. Evaluations of the same user are serialized so concurrent
+// submissions cannot both report an enter.
+func EvaluateGeofences(ctx context.Context, tenantID, userID string, locations []*Location) ([]GeofenceEvent, error) {
+	if len(locations) == 0 {
+		return nil, nil
+	}
+	points := append([]*Location(nil), locations...)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+
+	var events []GeofenceEvent
+	err := WithUserLock(ctx, "geofence", tenantID, userID, func(tx *sql.Tx) error {
+		var err error
+		events, err = evaluateGeofences(ctx, tx, tenantID, userID, points)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func evaluateGeofences(ctx context.Context, tx *sql.Tx, tenantID, userID string, points []*Location) ([]GeofenceEvent, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT geofence_id, entered_at, dwell_notified FROM geofence_states WHERE tenant_id = $1 AND user_id = $2`, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]*GeofenceState)
+	var inside []string
+	for rows.Next() {
+		var s GeofenceState
+		if err := rows.Scan(&s.GeofenceID, &s.EnteredAt, &s.DwellNotified); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		states[s.GeofenceID] = &s
+		inside = append(inside, s.GeofenceID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	geofences, err := candidateGeofences(ctx, tx, tenantID, points, inside)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []GeofenceEvent
+	changed := make(map[string]bool)
+	for _, l := range points {
+		for i := range geofences {
+			g := &geofences[i]
+			event, next := geofenceTransition(g, states[g.ID], l)
+			if event == "" {
+				continue
+			}
+			if next == nil {
+				delete(states, g.ID)
+			} else {
+				states[g.ID] = next
+			}
+			changed[g.ID] = true
+			events = append(events, GeofenceEvent{
+				TenantID:   tenantID,
+				UserID:     userID,
+				GeofenceID: g.ID,
+				Type:       event,
+				LocationID: l.ID,
+				Latitude:   l.Latitude,
+				Longitude:  l.Longitude,
+				OccurredAt: l.Timestamp,
+			})
+		}
+	}
+
+	for id := range changed {
+		s, ok := states[id]
+		if !ok {
+			_, err = tx.ExecContext(ctx, `DELETE FROM geofence_states WHERE tenant_id = $1 AND user_id = $2 AND geofence_id = $3`, tenantID, userID, id)
+		} else {
+			_, err = tx.ExecContext(ctx, `INSERT INTO geofence_states (tenant_id, user_id, geofence_id, entered_at, dwell_notified) VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (tenant_id, user_id, geofence_id) DO UPDATE SET entered_at = EXCLUDED.entered_at, dwell_notified = EXCLUDED.dwell_notified`,
+				tenantID, userID, id, s.EnteredAt, s.DwellNotified)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range events {
+		e := &events[i]
+		err := tx.QueryRowContext(ctx, `INSERT INTO geofence_events (tenant_id, user_id, geofence_id, type, location_id, latitude, longitude, occurred_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5::BIGINT, 0), $6, $7, $8) RETURNING id`,
+			e.TenantID, e.UserID, e.GeofenceID, e.Type, e.LocationID, e.Latitude, e.Longitude, e.OccurredAt).Scan(&e.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// GeofenceEventQuery filters the event history of a tenant. Results are
+// ordered by occurrence time, then ID.
+type GeofenceEventQuery struct {
+	pagination.Params
+	TenantID   string
+	UserID     string
+	GeofenceID string
+	Type       string
+	From       *time.Time
+	To         *time.Time
+}
+
+// QueryGeofenceEvents returns one page of the events matching q
+func QueryGeofenceEvents(ctx context.Context, q GeofenceEventQuery) (*pagination.Page[GeofenceEvent], error) {
+	if q.Limit <= 0 {
+		q.Limit = pagination.DefaultLimit
+	}
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{q.TenantID}
+	add := func(cond string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+	if q.UserID != "" {
+		add("user_id = $%d", q.UserID)
+	}
+	if q.GeofenceID != "" {
+		add("geofence_id = $%d", q.GeofenceID)
+	}
+	if q.Type != "" {
+		add("type = $%d", q.Type)
+	}
+	if q.From != nil {
+		add("occurred_at >= $%d", *q.From)
+	}
+	if q.To != nil {
+		add("occurred_at < $%d", *q.To)
+	}
+	if q.Cursor != nil {
+		ts, err := time.Parse(time.RFC3339Nano, q.Cursor.Value)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		id, err := strconv.ParseInt(q.Cursor.ID, 10, 64)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		args = append(args, ts, id)
+		conditions = append(conditions, fmt.Sprintf("(occurred_at, id) %s ($%d, $%d)", q.Comparator(), len(args)-1, len(args)))
+	}
+	args = append(args, q.Limit+1)
+	query := fmt.Sprintf(`SELECT id, tenant_id, user_id, geofence_id, type, COALESCE(location_id, 0), latitude, longitude, occurred_at
+		FROM geofence_events WHERE %s ORDER BY occurred_at %s, id %s LIMIT $%d`,
+		strings.Join(conditions, " AND "), q.Direction(), q.Direction(), len(args))
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []GeofenceEvent{}
+	for rows.Next() {
+		var e GeofenceEvent
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.UserID, &e.GeofenceID, &e.Type, &e.LocationID, &e.Latitude, &e.Longitude, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &pagination.Page[GeofenceEvent]{Items: events}
+	if len(events) > q.Limit {
+		page.Items = events[:q.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = pagination.EncodeCursor(pagination.Cursor{
+			Value: last.OccurredAt.Format(time.RFC3339Nano),
+			ID:    strconv.FormatInt(last.ID, 10),
+		})
+	}
+	return page, nil
+}
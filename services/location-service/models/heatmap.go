@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+)
+
+// HeatmapZoom is the zoom level of the cells counted by the hourly rollup.
+// Cells are about 150 m wide at the equator; coarser zoom levels are
+// summed from them.
+const HeatmapZoom = 18
+
+// HeatmapCell is the number of points in a map cell. X and Y are tile
+// coordinates at the zoom level the cells were requested at.
+type HeatmapCell struct {
+	X     int   `json:"x"`
+	Y     int   `json:"y"`
+	Count int64 `json:"count"`
+}
+
+// heatmapHour returns the hour of t as stored in TIMESTAMP columns, which
+// keep the wall clock of t
+func heatmapHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// execer runs statements on the database or in a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// AddHeatmapCounts adds newly stored locations to the hourly rollup
+func AddHeatmapCounts(ctx context.Context, locations []*Location) error {
+	return addHeatmapCounts(ctx, DB, locations)
+}
+
+func addHeatmapCounts(ctx context.Context, db execer, locations []*Location) error {
+	type key struct {
+		tenantID string
+		hour     time.Time
+		x, y     int
+	}
+	counts := make(map[key]int64)
+	var order []key
+	for _, l := range locations {
+		t := geometry.TileAt(geometry.Point{Lat: l.Latitude, Lon: l.Longitude}, HeatmapZoom)
+		k := key{l.TenantID, heatmapHour(l.Timestamp), t.X, t.Y}
+		if _, ok := counts[k]; !ok {
+			order = append(order, k)
+		}
+		counts[k]++
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(order))
+	args := make([]interface{}, 0, 5*len(order))
+	for i, k := range order {
+		n := len(args)
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5)
+		args = append(args, k.tenantID, k.hour, k.x, k.y, counts[k])
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO heatmap_hourly (tenant_id, hour, x, y, count) VALUES `+strings.Join(placeholders, ", ")+`
+		ON CONFLICT (tenant_id, hour, x, y) DO UPDATE SET count = heatmap_hourly.count + EXCLUDED.count`, args...)
+	return err
+}
+
+// QueryHeatmap sums the points of a tenant in the cells of zoom cellZoom
+// within tile t, over the hours starting in [from, to)
+func QueryHeatmap(ctx context.Context, tenantID string, t geometry.Tile, cellZoom int, from, to time.Time) ([]HeatmapCell, error) {
+	span := HeatmapZoom - t.Z
+	x0, y0 := t.X<<span, t.Y<<span
+	rows, err := DB.QueryContext(ctx, `SELECT x >> $2 AS cx, y >> $2 AS cy, SUM(count) FROM heatmap_hourly
+		WHERE tenant_id = $1 AND hour >= $3 AND hour < $4 AND x >= $5 AND x < $6 AND y >= $7 AND y < $8
+		GROUP BY cx, cy ORDER BY cy, cx`,
+		tenantID, HeatmapZoom-cellZoom, from, to, x0, x0+1<<span, y0, y0+1<<span)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cells := []HeatmapCell{}
+	for rows.Next() {
+		var c HeatmapCell
+		if err := rows.Scan(&c.X, &c.Y, &c.Count); err != nil {
+			return nil, err
+		}
+		cells = append(cells, c)
+	}
+	return cells, rows.Err()
+}
+
+// RebuildHeatmap recomputes the rollup of a tenant for the hours starting
+// in [from, to) from the stored locations, and returns the number of
+// points counted. Points stored for the range while it runs may be counted
+// twice, so it is meant for past hours. Plain points are counted in SQL;
+// sealed ones are decrypted and counted here.
+func RebuildHeatmap(ctx context.Context, tenantID string, from, to time.Time) (int64, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM heatmap_hourly WHERE tenant_id = $1 AND hour >= $2 AND hour < $3`, tenantID, from, to); err != nil {
+		return 0, err
+	}
+	// The same cell formula as geometry.TileAt
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO heatmap_hourly (tenant_id, hour, x, y, count)
+		SELECT tenant_id, hour, x, y, COUNT(*) FROM (
+			SELECT tenant_id, date_trunc('hour', timestamp) AS hour,
+				LEAST(GREATEST(FLOOR((longitude + 180) / 360 * %[1]d), 0), %[1]d - 1)::INTEGER AS x,
+				LEAST(GREATEST(FLOOR((1 - LN(TAN(RADIANS(lat)) + 1 / COS(RADIANS(lat))) / PI()) / 2 * %[1]d), 0), %[1]d - 1)::INTEGER AS y
+			FROM (SELECT tenant_id, timestamp, longitude, LEAST(GREATEST(latitude, -%[2]v), %[2]v) AS lat
+				FROM locations WHERE tenant_id = $1 AND timestamp >= $2 AND timestamp < $3 AND ciphertext IS NULL) p
+		) cells GROUP BY tenant_id, hour, x, y`, 1<<HeatmapZoom, geometry.MaxMercatorLat), tenantID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	if err := addSealedHeatmapCounts(ctx, tx, tenantID, from, to); err != nil {
+		return 0, err
+	}
+	var counted int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(count), 0) FROM heatmap_hourly WHERE tenant_id = $1 AND hour >= $2 AND hour < $3`,
+		tenantID, from, to).Scan(&counted); err != nil {
+		return 0, err
+	}
+	return counted, tx.Commit()
+}
+
+// addSealedHeatmapCounts adds the sealed locations of a tenant with a
+// timestamp in [from, to) to the rollup, a batch at a time
+func addSealedHeatmapCounts(ctx context.Context, tx *sql.Tx, tenantID string, from, to time.Time) error {
+	if keyring == nil {
+		return nil
+	}
+	afterTime, afterID := from, int64(0)
+	for {
+		// A transaction runs one statement at a time, so each batch is read
+		// in full before it is counted
+		rows, err := tx.QueryContext(ctx, `SELECT `+locationSelectColumns+` FROM locations
+			WHERE tenant_id = $1 AND (timestamp, id) > ($2, $3) AND timestamp < $4 AND ciphertext IS NOT NULL
+			ORDER BY timestamp, id LIMIT $5`, tenantID, afterTime, afterID, to, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		var batch []*Location
+		for rows.Next() {
+			l, err := scanLocation(ctx, rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, &l)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := addHeatmapCounts(ctx, tx, batch); err != nil {
+			return err
+		}
+		last := batch[len(batch)-1]
+		afterTime, afterID = last.Timestamp, last.ID
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/validation"
+)
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// MaxImportErrors bounds the line errors kept per job; later failures are
+// only counted
+const MaxImportErrors = 1000
+
+// ImportLineError describes a record of an import file that was not stored
+type ImportLineError struct {
+	Line   int               `json:"line"`
+	Error  string            `json:"error,omitempty"`
+	Fields validation.Errors `json:"fields,omitempty"`
+}
+
+// ImportJob tracks the asynchronous import of a historical track file for
+// one tenant user. Progress is reported as bytes read out of SizeBytes.
+type ImportJob struct {
+	ID         string            `json:"id"`
+	TenantID   string            `json:"tenant_id"`
+	UserID     string            `json:"user_id"`
+	Format     string            `json:"format"`
+	Replay     bool              `json:"replay"`
+	Status     string            `json:"status"`
+	SizeBytes  int64             `json:"size_bytes"`
+	ReadBytes  int64             `json:"read_bytes"`
+	Processed  int               `json:"processed"`
+	Imported   int               `json:"imported"`
+	Duplicates int               `json:"duplicates"`
+	Discarded  int               `json:"discarded"` // Dropped by the privacy policy
+	Failed     int               `json:"failed"`
+	Errors     []ImportLineError `json:"errors,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+func NewImportJob(tenantID, userID, format string, replay bool, size int64) *ImportJob {
+	now := time.Now()
+	return &ImportJob{
+		ID:        newID(),
+		TenantID:  tenantID,
+		UserID:    userID,
+		Format:    format,
+		Replay:    replay,
+		Status:    ImportStatusPending,
+		SizeBytes: size,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// AddError counts a rejected record and keeps its error while fewer than
+// MaxImportErrors are held
+func (j *ImportJob) AddError(line int, err error) {
+	j.Failed++
+	if len(j.Errors) >= MaxImportErrors {
+		return
+	}
+	e := ImportLineError{Line: line}
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		e.Fields = fields
+	} else {
+		e.Error = err.Error()
+	}
+	j.Errors = append(j.Errors, e)
+}
+
+// Finish marks the job completed, or failed when err is not nil
+func (j *ImportJob) Finish(err error) {
+	now := time.Now()
+	j.Status = ImportStatusCompleted
+	if err != nil {
+		j.Status = ImportStatusFailed
+		j.Error = err.Error()
+	}
+	j.FinishedAt = &now
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrImportJobNotFound = errors.New("import job not found")
+
+func SaveImportJob(ctx context.Context, j *ImportJob) error {
+	_, err := DB.ExecContext(ctx, `INSERT INTO import_jobs (id, tenant_id, user_id, format, replay, status, size_bytes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		j.ID, j.TenantID, j.UserID, j.Format, j.Replay, j.Status, j.SizeBytes, j.CreatedAt, j.UpdatedAt)
+	return err
+}
+
+// UpdateImportJob stores the status and progress of j
+func UpdateImportJob(ctx context.Context, j *ImportJob) error {
+	var lineErrors interface{}
+	if len(j.Errors) > 0 {
+		b, err := json.Marshal(j.Errors)
+		if err != nil {
+			return err
+		}
+		lineErrors = string(b)
+	}
+	j.UpdatedAt = time.Now()
+	_, err := DB.ExecContext(ctx, `UPDATE import_jobs SET status = $2, read_bytes = $3, processed = $4, imported = $5, duplicates = $6,
+		discarded = $7, failed = $8, errors = $9, error = NULLIF($10, ''), updated_at = $11, finished_at = $12 WHERE id = $1`,
+		j.ID, j.Status, j.ReadBytes, j.Processed, j.Imported, j.Duplicates, j.Discarded, j.Failed, lineErrors, j.Error, j.UpdatedAt, j.FinishedAt)
+	return err
+}
+
+const importJobColumns = `id, tenant_id, user_id, format, replay, status, size_bytes, read_bytes, processed, imported,
+	duplicates, discarded, failed, errors, COALESCE(error, ''), created_at, updated_at, finished_at`
+
+func scanImportJob(row rowScanner) (*ImportJob, error) {
+	var j ImportJob
+	var lineErrors []byte
+	var finishedAt sql.NullTime
+	if err := row.Scan(&j.ID, &j.TenantID, &j.UserID, &j.Format, &j.Replay, &j.Status, &j.SizeBytes, &j.ReadBytes, &j.Processed, &j.Imported,
+		&j.Duplicates, &j.Discarded, &j.Failed, &lineErrors, &j.Error, &j.CreatedAt, &j.UpdatedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	if len(lineErrors) > 0 {
+		if err := json.Unmarshal(lineErrors, &j.Errors); err != nil {
+			return nil, err
+		}
+	}
+	if finishedAt.Valid {
+		j.FinishedAt = &finishedAt.Time
+	}
+	return &j, nil
+}
+
+// GetImportJob returns an import job belonging to tenantID
+func GetImportJob(ctx context.Context, tenantID, id string) (*ImportJob, error) {
+	j, err := scanImportJob(DB.QueryRowContext(ctx, `SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1 AND tenant_id = $2`, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrImportJobNotFound
+	}
+	return j, err
+}
+
+// ListUserImportJobs returns the import jobs of a tenant user, oldest first
+func ListUserImportJobs(ctx context.Context, tenantID, userID string) ([]ImportJob, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT `+importJobColumns+` FROM import_jobs WHERE tenant_id = $1 AND user_id = $2
+		ORDER BY created_at, id`, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	jobs := []ImportJob{}
+	for rows.Next() {
+		j, err := scanImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, rows.Err()
+}
+
+// FailInterruptedImportJobs marks jobs left unfinished by a previous process
+// as failed. Jobs run inside the service process and do not survive a
+// restart.
+func FailInterruptedImportJobs(ctx context.Context) (int64, error) {
+	res, err := DB.ExecContext(ctx, `UPDATE import_jobs SET status = $1, error = 'interrupted by service restart', updated_at = NOW(), finished_at = NOW()
+		WHERE status IN ($2, $3)`, ImportStatusFailed, ImportStatusPending, ImportStatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
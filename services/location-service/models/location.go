@@ -4,8 +4,15 @@ import "time"
 
 // Location represents the geographical location data submitted by a tenant user.
 type Location struct {
-    Latitude  float64   `json:"latitude"`
-    Longitude float64   `json:"longitude"`
-    Timestamp time.Time `json:"timestamp"`
-    TenantID  string    `json:"tenant_id"` // Identifier for the tenant to which this location data belongs
+    ID            int64     `json:"id,omitempty"`
+    Latitude      float64   `json:"latitude"`
+    Longitude     float64   `json:"longitude"`
+    Timestamp     time.Time `json:"timestamp"`
+    TenantID      string    `json:"tenant_id"` // Identifier for the tenant to which this location data belongs
+    UserID        string    `json:"user_id,omitempty"`
+    SessionID     string    `json:"session_id,omitempty"`
+    ClientPointID string    `json:"client_point_id,omitempty"` // Client-supplied ID used to deduplicate retries
+    Telemetry
+    RiskScore     *float64  `json:"risk_score,omitempty"` // Spoofing risk from 0 to 1, set on ingest
+    Anomalies     []string  `json:"anomalies,omitempty"`  // Checks the point failed, e.g. teleport
 }
\ No newline at end of file
@@ -2,29 +2,375 @@ package models
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrDuplicateLocation is returned when a location with the same client
+// point ID was already stored for the tenant user
+var ErrDuplicateLocation = errors.New("duplicate location")
+
+const (
+	locationInsertColumns = `latitude, longitude, timestamp, tenant_id, user_id, session_id, client_point_id, accuracy, altitude, speed, heading, battery_level, provider, metadata, risk_score, anomalies, key_version, ciphertext`
+	locationSelectColumns = `id, latitude, longitude, timestamp, tenant_id, COALESCE(user_id, ''), COALESCE(session_id, ''), COALESCE(client_point_id, ''), accuracy, altitude, speed, heading, battery_level, COALESCE(provider, ''), metadata, risk_score, anomalies, key_version, ciphertext`
 )
 
+// locationInsertParams is the number of bind parameters of a row of
+// locationInsertRow
+const locationInsertParams = 18
+
+// MaxSaveLocations is the largest number of locations SaveLocations stores,
+// as Postgres limits the bind parameters of its single statement
+const MaxSaveLocations = maxQueryParams / locationInsertParams
+
+// locationInsertColumnList returns the columns written by inserts, including
+// the geography column when PostGIS is available
+func locationInsertColumnList() string {
+	if PostGIS {
+		return locationInsertColumns + ", geog"
+	}
+	return locationInsertColumns
+}
+
+// locationInsertRow returns the placeholders and arguments of l starting at
+// parameter $n+1, matching locationInsertColumnList
+func locationInsertRow(ctx context.Context, l *Location, n int) (string, []interface{}, error) {
+	latitude, longitude, metadata, keyVersion, ciphertext, err := storedColumns(ctx, l, keyring != nil)
+	if err != nil {
+		return "", nil, err
+	}
+	var anomalies interface{}
+	if len(l.Anomalies) > 0 {
+		anomalies = pq.Array(l.Anomalies)
+	}
+	values := fmt.Sprintf("$%d, $%d, $%d, $%d, NULLIF($%d, ''), NULLIF($%d, ''), NULLIF($%d, ''), $%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d, $%d, $%d, $%d, $%d",
+		n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12, n+13, n+14, n+15, n+16, n+17, n+18)
+	if PostGIS {
+		if ciphertext != nil {
+			// A geography would give the sealed position away. Geofences
+			// are matched against the received coordinates, history bbox
+			// filters and heatmap rebuilds decrypt, and nearby searches use
+			// the latest-location cache while encryption is enabled.
+			values += ", NULL"
+		} else {
+			values += ", " + pointGeography(fmt.Sprintf("$%d", n+2), fmt.Sprintf("$%d", n+1))
+		}
+	}
+	args := []interface{}{latitude, longitude, l.Timestamp, l.TenantID, l.UserID, l.SessionID, l.ClientPointID,
+		l.Accuracy, l.Altitude, l.Speed, l.Heading, l.BatteryLevel, l.Provider, metadata, l.RiskScore, anomalies, keyVersion, ciphertext}
+	return "(" + values + ")", args, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanLocation reads a row selected with locationSelectColumns, decrypting
+// sealed coordinates and metadata
+func scanLocation(ctx context.Context, row rowScanner) (Location, error) {
+	var l Location
+	var latitude, longitude, accuracy, altitude, speed, heading, battery, riskScore sql.NullFloat64
+	var metadata, ciphertext []byte
+	var anomalies pq.StringArray
+	var keyVersion sql.NullInt64
+	if err := row.Scan(&l.ID, &latitude, &longitude, &l.Timestamp, &l.TenantID, &l.UserID, &l.SessionID, &l.ClientPointID,
+		&accuracy, &altitude, &speed, &heading, &battery, &l.Provider, &metadata, &riskScore, &anomalies, &keyVersion, &ciphertext); err != nil {
+		return l, err
+	}
+	l.Latitude, l.Longitude = latitude.Float64, longitude.Float64
+	l.Accuracy = floatPtr(accuracy)
+	l.Altitude = floatPtr(altitude)
+	l.Speed = floatPtr(speed)
+	l.Heading = floatPtr(heading)
+	l.BatteryLevel = floatPtr(battery)
+	l.RiskScore = floatPtr(riskScore)
+	if len(anomalies) > 0 {
+		l.Anomalies = anomalies
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &l.Metadata); err != nil {
+			return l, err
+		}
+	}
+	if ciphertext != nil {
+		if err := openLocation(ctx, &l, int(keyVersion.Int64), ciphertext); err != nil {
+			return l, err
+		}
+	}
+	return l, nil
+}
+
+// storedColumns returns the latitude, longitude, metadata, key_version and
+// ciphertext values of l. Sealed locations only keep the ciphertext.
+func storedColumns(ctx context.Context, l *Location, seal bool) (latitude, longitude, metadata, keyVersion, ciphertext interface{}, err error) {
+	if seal {
+		version, sealed, err := sealLocation(ctx, l)
+		if err != nil {
+			return nil, nil, nil, nil, nil, err
+		}
+		return nil, nil, nil, version, sealed, nil
+	}
+	if len(l.Metadata) > 0 {
+		// JSONB is sent as text; a []byte argument would be encoded as bytea
+		b, err := json.Marshal(l.Metadata)
+		if err != nil {
+			return nil, nil, nil, nil, nil, err
+		}
+		metadata = string(b)
+	}
+	return l.Latitude, l.Longitude, metadata, nil, nil, nil
+}
+
+func floatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+// SaveLocation stores a location, or returns ErrDuplicateLocation when its
+// client point ID is already claimed. Claims are checked by the insert
+// trigger on locations, as unique indexes cannot span its partitions. The
+// location is queued in the outbox in the same transaction.
 func SaveLocation(ctx context.Context, l *Location) error {
-	_, err := DB.ExecContext(ctx, `INSERT INTO locations (latitude, longitude, timestamp, tenant_id) VALUES ($1, $2, $3, $4)`, l.Latitude, l.Longitude, l.Timestamp, l.TenantID)
-	return err
+	placeholders, args, err := locationInsertRow(ctx, l, 0)
+	if err != nil {
+		return err
+	}
+	return withOutbox(ctx, true, func(db queryer) ([]*Location, error) {
+		err := db.QueryRowContext(ctx, `INSERT INTO locations (`+locationInsertColumnList()+`) VALUES `+placeholders+`
+			RETURNING id`, args...).Scan(&l.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDuplicateLocation
+		}
+		return []*Location{l}, err
+	})
+}
+
+// SaveLocations inserts all locations with a single multi-row statement.
+// Locations whose client point ID already exists are skipped and keep a
+// zero ID. The stored locations are queued in the outbox in the same
+// transaction.
+func SaveLocations(ctx context.Context, locations []*Location) error {
+	if len(locations) == 0 {
+		return nil
+	}
+	if len(locations) > MaxSaveLocations {
+		return fmt.Errorf("cannot save %d locations in one statement, at most %d", len(locations), MaxSaveLocations)
+	}
+	return withOutbox(ctx, true, func(db queryer) ([]*Location, error) {
+		// IDs are drawn before the insert, as the order of returned rows is
+		// not defined; the returned IDs tell which locations were stored
+		ids, err := nextLocationIDs(ctx, db, len(locations))
+		if err != nil {
+			return nil, err
+		}
+		placeholders := make([]string, 0, len(locations))
+		var args []interface{}
+		byID := make(map[int64]*Location, len(locations))
+		for i, l := range locations {
+			row, rowArgs, err := locationInsertRow(ctx, l, len(args))
+			if err != nil {
+				return nil, err
+			}
+			placeholders = append(placeholders, fmt.Sprintf("(%d, %s", ids[i], strings.TrimPrefix(row, "(")))
+			args = append(args, rowArgs...)
+			byID[ids[i]] = l
+			l.ID = 0
+		}
+		rows, err := db.QueryContext(ctx, `INSERT INTO locations (id, `+locationInsertColumnList()+`) VALUES `+strings.Join(placeholders, ", ")+`
+			RETURNING id`, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var stored []*Location
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			if l, ok := byID[id]; ok {
+				l.ID = id
+				stored = append(stored, l)
+			}
+		}
+		return stored, rows.Err()
+	})
+}
+
+// nextLocationIDs draws n IDs from the sequence of locations
+func nextLocationIDs(ctx context.Context, db queryer, n int) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT nextval(pg_get_serial_sequence('locations', 'id')) FROM generate_series(1, $1)`, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := make([]int64, 0, n)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) != n {
+		return nil, fmt.Errorf("drew %d location IDs, expected %d", len(ids), n)
+	}
+	return ids, nil
+}
+
+// GetLocationsByClientPointIDs returns the stored locations of a tenant user
+// keyed by client point ID
+func GetLocationsByClientPointIDs(ctx context.Context, tenantID, userID string, clientPointIDs []string) (map[string]Location, error) {
+	result := make(map[string]Location)
+	if len(clientPointIDs) == 0 {
+		return result, nil
+	}
+	rows, err := DB.QueryContext(ctx, `SELECT `+locationSelectColumns+` FROM locations
+		WHERE tenant_id = $1 AND user_id = $2 AND client_point_id = ANY($3)`, tenantID, userID, pq.Array(clientPointIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		l, err := scanLocation(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		result[l.ClientPointID] = l
+	}
+	return result, rows.Err()
 }
 
 func ListLocationsByTenant(ctx context.Context, tenantID string) ([]Location, error) {
-	rows, err := DB.QueryContext(ctx, `SELECT latitude, longitude, timestamp, tenant_id FROM locations WHERE tenant_id = $1`, tenantID)
+	rows, err := DB.QueryContext(ctx, `SELECT `+locationSelectColumns+` FROM locations WHERE tenant_id = $1`, tenantID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var locations []Location
 	for rows.Next() {
-		var l Location
-		var ts time.Time
-		if err := rows.Scan(&l.Latitude, &l.Longitude, &ts, &l.TenantID); err != nil {
+		l, err := scanLocation(ctx, rows)
+		if err != nil {
 			return nil, err
 		}
-		l.Timestamp = ts
 		locations = append(locations, l)
 	}
-	return locations, nil
+	return locations, rows.Err()
+}
+
+// ListLatestLocations returns the most recent location of every user with a
+// point at or after since. The bound keeps the scan to the recent partitions.
+func ListLatestLocations(ctx context.Context, since time.Time) ([]Location, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT DISTINCT ON (tenant_id, user_id) `+locationSelectColumns+` FROM locations
+		WHERE user_id IS NOT NULL AND timestamp >= $1 ORDER BY tenant_id, user_id, timestamp DESC, id DESC`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var locations []Location
+	for rows.Next() {
+		l, err := scanLocation(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, l)
+	}
+	return locations, rows.Err()
+}
+
+// CopyLocations bulk loads locations with COPY into a temporary table, then
+// moves them into locations skipping client point IDs that already exist.
+// Inserted locations get their IDs set; the skipped ones keep a zero ID.
+// Every location must carry a client point ID. With publish set the
+// inserted locations are queued in the outbox in the same transaction.
+func CopyLocations(ctx context.Context, locations []*Location, publish bool) (int, error) {
+	if len(locations) == 0 {
+		return 0, nil
+	}
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE location_import ON COMMIT DROP AS
+		SELECT `+locationInsertColumns+` FROM locations WITH NO DATA`); err != nil {
+		return 0, err
+	}
+	columns := strings.Split(locationInsertColumns, ", ")
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("location_import", columns...))
+	if err != nil {
+		return 0, err
+	}
+	byClientPointID := make(map[string]*Location, len(locations))
+	for _, l := range locations {
+		_, args, err := locationInsertRow(ctx, l, 0)
+		if err != nil {
+			stmt.Close()
+			return 0, err
+		}
+		// NULLIF is applied by the placeholders of regular inserts
+		for i, arg := range args {
+			if s, ok := arg.(string); ok && s == "" {
+				args[i] = nil
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			stmt.Close()
+			return 0, err
+		}
+		byClientPointID[l.ClientPointID] = l
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return 0, err
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, err
+	}
+
+	selectColumns := locationInsertColumns
+	if PostGIS {
+		selectColumns += ", " + pointGeography("longitude", "latitude")
+	}
+	rows, err := tx.QueryContext(ctx, `INSERT INTO locations (`+locationInsertColumnList()+`) SELECT `+selectColumns+` FROM location_import
+		RETURNING id, client_point_id`)
+	if err != nil {
+		return 0, err
+	}
+	var stored []*Location
+	for rows.Next() {
+		var id int64
+		var clientPointID string
+		if err := rows.Scan(&id, &clientPointID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if l, ok := byClientPointID[clientPointID]; ok {
+			l.ID = id
+			stored = append(stored, l)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if publish {
+		if err := enqueueLocations(ctx, tx, stored); err != nil {
+			return 0, err
+		}
+	}
+	return len(stored), tx.Commit()
 }
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+)
+
+// BoundingBox selects points by coordinates. MinLon greater than MaxLon
+// describes a box crossing the antimeridian.
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// Contains reports whether a position lies in b, edges included
+func (b *BoundingBox) Contains(lat, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return lon >= b.MinLon && lon <= b.MaxLon
+	}
+	return lon >= b.MinLon || lon <= b.MaxLon
+}
+
+// LocationQuery filters the location history of a tenant. Results are
+// ordered by timestamp, then ID.
+type LocationQuery struct {
+	pagination.Params
+	TenantID  string
+	UserID    string
+	SessionID string
+	From      *time.Time
+	To        *time.Time
+	BBox      *BoundingBox
+}
+
+// where returns the conditions and arguments selecting q, cursor excluded
+func (q *LocationQuery) where() ([]string, []interface{}) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{q.TenantID}
+	add := func(cond string, values ...interface{}) {
+		for _, v := range values {
+			args = append(args, v)
+			cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+		conditions = append(conditions, cond)
+	}
+	if q.UserID != "" {
+		add("user_id = ?", q.UserID)
+	}
+	if q.SessionID != "" {
+		add("session_id = ?", q.SessionID)
+	}
+	if q.From != nil {
+		add("timestamp >= ?", *q.From)
+	}
+	if q.To != nil {
+		add("timestamp < ?", *q.To)
+	}
+	if b := q.BBox; b != nil {
+		first := len(conditions)
+		// The geography index narrows the rows; the coordinate conditions
+		// keep the box edges exact
+		if envelope, ok := bboxGeography(b, "?", "?", "?", "?"); ok && PostGIS {
+			add("geog && "+envelope, b.MinLon, b.MinLat, b.MaxLon, b.MaxLat)
+		}
+		add("latitude BETWEEN ? AND ?", b.MinLat, b.MaxLat)
+		if b.MinLon <= b.MaxLon {
+			add("longitude BETWEEN ? AND ?", b.MinLon, b.MaxLon)
+		} else {
+			add("(longitude >= ? OR longitude <= ?)", b.MinLon, b.MaxLon)
+		}
+		if keyring != nil {
+			// Sealed locations have no coordinates to filter on; they are
+			// checked once decrypted
+			box := "(ciphertext IS NOT NULL OR (" + strings.Join(conditions[first:], " AND ") + "))"
+			conditions = append(conditions[:first], box)
+		}
+	}
+	return conditions, args
+}
+
+// QueryLocations returns one page of the location history matching q.
+// Sealed locations outside q.BBox are left out after decryption, so such
+// a page may hold fewer than q.Limit items and still have a next cursor.
+func QueryLocations(ctx context.Context, q LocationQuery) (*pagination.Page[Location], error) {
+	if q.Limit <= 0 {
+		q.Limit = pagination.DefaultLimit
+	}
+	conditions, args := q.where()
+	if q.Cursor != nil {
+		ts, err := time.Parse(time.RFC3339Nano, q.Cursor.Value)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		id, err := strconv.ParseInt(q.Cursor.ID, 10, 64)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		args = append(args, ts, id)
+		conditions = append(conditions, fmt.Sprintf("(timestamp, id) %s ($%d, $%d)", q.Comparator(), len(args)-1, len(args)))
+	}
+	args = append(args, q.Limit+1)
+	query := fmt.Sprintf(`SELECT %s FROM locations WHERE %s ORDER BY timestamp %s, id %s LIMIT $%d`,
+		locationSelectColumns, strings.Join(conditions, " AND "), q.Direction(), q.Direction(), len(args))
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	locations := []Location{}
+	var last Location
+	scanned, more := 0, false
+	for rows.Next() {
+		if scanned == q.Limit {
+			more = true
+			break
+		}
+		l, err := scanLocation(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		scanned++
+		last = l
+		if q.BBox != nil && !q.BBox.Contains(l.Latitude, l.Longitude) {
+			continue
+		}
+		locations = append(locations, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &pagination.Page[Location]{Items: locations}
+	if more {
+		page.NextCursor = pagination.EncodeCursor(pagination.Cursor{
+			Value: last.Timestamp.Format(time.RFC3339Nano),
+			ID:    strconv.FormatInt(last.ID, 10),
+		})
+	}
+	return page, nil
+}
+
+// exportBatchSize is the page size used by EachLocation
+const exportBatchSize = 1000
+
+// EachLocation calls fn for every location matching q, in order. Rows are
+// read page by page with a keyset cursor, so memory use does not depend on
+// the number of matches. q.Limit and q.Cursor are ignored.
+func EachLocation(ctx context.Context, q LocationQuery, fn func(Location) error) error {
+	q.Limit = exportBatchSize
+	q.Cursor = nil
+	for {
+		page, err := QueryLocations(ctx, q)
+		if err != nil {
+			return err
+		}
+		for _, l := range page.Items {
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		if q.Cursor, err = pagination.DecodeCursor(page.NextCursor); err != nil {
+			return err
+		}
+	}
+}
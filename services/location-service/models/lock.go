@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// WithUserLock runs fn in a transaction holding an advisory lock on a tenant
+// user within scope, so work on the same user is serialized across
+// replicas. The transaction is committed when fn succeeds.
+func WithUserLock(ctx context.Context, scope, tenantID, userID string, fn func(tx *sql.Tx) error) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, scope+":"+tenantID+"/"+userID); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TryWithLock runs fn while holding a session advisory lock on scope, unless
+// another replica holds it, in which case fn is skipped and false returned
+func TryWithLock(ctx context.Context, scope string, fn func() error) (bool, error) {
+	conn, err := DB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, scope).Scan(&locked); err != nil || !locked {
+		return false, err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, scope)
+	return true, fn()
+}
@@ -0,0 +1,51 @@
+package models
+
+import "context"
+
+// NearbyLocation is the latest location of a user with its distance from
+// the searched point
+type NearbyLocation struct {
+	Location
+	DistanceMeters float64 `json:"distance_m"`
+}
+
+// QueryNearbyLocations returns the latest locations of a tenant's users
+// within radiusMeters of lat, lon, closest first. Points within the radius
+// are found through the geography index, keeping those that are their
+// user's latest. Distances are computed on the sphere like Haversine. It
+// requires PostGIS.
+func QueryNearbyLocations(ctx context.Context, tenantID string, lat, lon, radiusMeters float64, limit int) ([]NearbyLocation, error) {
+	center := pointGeography("$2", "$3")
+	rows, err := DB.QueryContext(ctx, `SELECT `+locationSelectColumns+`, distance FROM (
+			SELECT l.*, ST_Distance(l.geog, `+center+`, false) AS distance FROM locations l
+			WHERE l.tenant_id = $1 AND l.user_id IS NOT NULL AND ST_DWithin(l.geog, `+center+`, $4, false)
+				AND NOT EXISTS (SELECT 1 FROM locations n WHERE n.tenant_id = l.tenant_id AND n.user_id = l.user_id
+					AND (n.timestamp, n.id) > (l.timestamp, l.id))
+		) nearby ORDER BY distance, user_id LIMIT $5`, tenantID, lon, lat, radiusMeters, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	nearby := []NearbyLocation{}
+	for rows.Next() {
+		var n NearbyLocation
+		var distance float64
+		l, err := scanLocation(ctx, scanFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &distance)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+		n.Location, n.DistanceMeters = l, distance
+		nearby = append(nearby, n)
+	}
+	return nearby, rows.Err()
+}
+
+// scanFunc adapts a function to rowScanner, e.g. to scan extra columns
+// after those read by scanLocation
+type scanFunc func(dest ...interface{}) error
+
+func (f scanFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// queryer runs queries on the database or in a transaction
+type queryer interface {
+	execer
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// withOutbox runs save on the database or, with publish set, in a
+// transaction that also queues the locations save stored in the outbox
+func withOutbox(ctx context.Context, publish bool, save func(db queryer) ([]*Location, error)) error {
+	if !publish {
+		_, err := save(DB)
+		return err
+	}
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stored, err := save(tx)
+	if err != nil {
+		return err
+	}
+	if err := enqueueLocations(ctx, tx, stored); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// maxQueryParams is the number of bind parameters Postgres accepts per
+// statement
+const maxQueryParams = 65535
+
+// enqueueLocations adds stored locations to the outbox. Entries refer to
+// the location rather than copy it, so sealed coordinates stay sealed and
+// erased locations are never published.
+func enqueueLocations(ctx context.Context, db execer, locations []*Location) error {
+	const rowsPerInsert = maxQueryParams / 4
+	for len(locations) > 0 {
+		chunk := locations[:min(len(locations), rowsPerInsert)]
+		locations = locations[len(chunk):]
+		placeholders := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, 4*len(chunk))
+		for _, l := range chunk {
+			n := len(args)
+			placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4))
+			args = append(args, l.TenantID, l.UserID, l.ID, l.Timestamp)
+		}
+		if _, err := db.ExecContext(ctx, `INSERT INTO location_outbox (tenant_id, user_id, location_id, location_timestamp) VALUES `+
+			strings.Join(placeholders, ", "), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RelayOutbox claims up to limit due outbox entries, oldest first, and
+// passes their locations to publish. Entries claimed by another replica are
+// skipped. Entries are marked sent when publish succeeds, and otherwise
+// retried after backoff of their number of failed attempts. Entries whose
+// location has been deleted since are dropped. It returns the number of
+// entries claimed.
+func RelayOutbox(ctx context.Context, limit int, backoff func(attempts int) time.Duration, publish func(ctx context.Context, locations []*Location) error) (int, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, location_id, location_timestamp, attempts FROM location_outbox
+		WHERE sent_at IS NULL AND next_attempt_at <= $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids, locationIDs []int64
+	var attempts int
+	var from, to time.Time
+	for rows.Next() {
+		var id, locationID int64
+		var ts time.Time
+		var n int
+		if err := rows.Scan(&id, &locationID, &ts, &n); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if len(ids) == 0 || ts.Before(from) {
+			from = ts
+		}
+		if len(ids) == 0 || ts.After(to) {
+			to = ts
+		}
+		ids = append(ids, id)
+		locationIDs = append(locationIDs, locationID)
+		// Entries fail together, so they are retried at the pace of the
+		// one that failed most
+		if n > attempts {
+			attempts = n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// The timestamp range limits the lookup to the partitions involved
+	rows, err = tx.QueryContext(ctx, `SELECT `+locationSelectColumns+` FROM locations
+		WHERE id = ANY($1) AND timestamp BETWEEN $2 AND $3`, pq.Array(locationIDs), from, to)
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[int64]*Location, len(locationIDs))
+	for rows.Next() {
+		l, err := scanLocation(ctx, rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		byID[l.ID] = &l
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	locations := make([]*Location, 0, len(locationIDs))
+	for _, id := range locationIDs {
+		if l, ok := byID[id]; ok {
+			locations = append(locations, l)
+		}
+	}
+
+	if len(locations) < len(locationIDs) {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM location_outbox WHERE id = ANY($1)
+			AND NOT EXISTS (SELECT 1 FROM locations l WHERE l.id = location_id AND l.timestamp = location_timestamp)`, pq.Array(ids)); err != nil {
+			return 0, err
+		}
+	}
+	if len(locations) > 0 {
+		if err := publish(ctx, locations); err != nil {
+			if _, updateErr := tx.ExecContext(ctx, `UPDATE location_outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+				WHERE id = ANY($1) AND sent_at IS NULL`, pq.Array(ids), err.Error(), time.Now().Add(backoff(attempts+1))); updateErr != nil {
+				return 0, updateErr
+			}
+			if commitErr := tx.Commit(); commitErr != nil {
+				return 0, commitErr
+			}
+			return len(ids), err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE location_outbox SET sent_at = $2 WHERE id = ANY($1) AND sent_at IS NULL`,
+			pq.Array(ids), time.Now()); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), tx.Commit()
+}
+
+// PurgeSentOutbox deletes the entries sent before the given time and
+// returns their number
+func PurgeSentOutbox(ctx context.Context, before time.Time) (int64, error) {
+	res, err := DB.ExecContext(ctx, `DELETE FROM location_outbox WHERE sent_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
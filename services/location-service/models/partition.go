@@ -0,0 +1,136 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// locations is partitioned by month of timestamp into tables named
+// locations_YYYY_MM, with locations_default holding points of months that
+// have no partition
+const (
+	locationPartitionPrefix  = "locations_"
+	locationPartitionLayout  = "2006_01"
+	locationDefaultPartition = "locations_default"
+)
+
+// LocationPartition is the partition holding the points of one month
+type LocationPartition struct {
+	Name string
+	// Points with From <= timestamp < To
+	From time.Time
+	To   time.Time
+}
+
+// MonthStart returns the first instant of the month of t, in UTC as
+// timestamps are stored without time zone
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func locationPartitionFor(month time.Time) LocationPartition {
+	from := MonthStart(month)
+	return LocationPartition{
+		Name: locationPartitionPrefix + from.Format(locationPartitionLayout),
+		From: from,
+		To:   from.AddDate(0, 1, 0),
+	}
+}
+
+// ListLocationPartitions returns the monthly partitions of locations in
+// month order
+func ListLocationPartitions(ctx context.Context) ([]LocationPartition, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'locations'::regclass ORDER BY c.relname`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var partitions []LocationPartition
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		month, err := time.Parse(locationPartitionLayout, strings.TrimPrefix(name, locationPartitionPrefix))
+		if err != nil {
+			// The default partition, or one not created by the service
+			continue
+		}
+		partitions = append(partitions, locationPartitionFor(month))
+	}
+	return partitions, rows.Err()
+}
+
+// DefaultPartitionMonths returns the months of the points held by the
+// default partition
+func DefaultPartitionMonths(ctx context.Context) ([]time.Time, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT DISTINCT date_trunc('month', timestamp) AS month FROM `+locationDefaultPartition+` ORDER BY month`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var months []time.Time
+	for rows.Next() {
+		var month time.Time
+		if err := rows.Scan(&month); err != nil {
+			return nil, err
+		}
+		months = append(months, MonthStart(month))
+	}
+	return months, rows.Err()
+}
+
+// CreateLocationPartition creates the partition of a month, moving its
+// points out of the default partition. It reports false for a partition
+// that already exists.
+func CreateLocationPartition(ctx context.Context, month time.Time) (LocationPartition, bool, error) {
+	p := locationPartitionFor(month)
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return p, false, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, p.Name).Scan(&exists); err != nil || exists {
+		return p, false, err
+	}
+	// Points cannot be added to the default partition while they move
+	from, to := p.From.Format("2006-01-02"), p.To.Format("2006-01-02")
+	for _, stmt := range []string{
+		`LOCK TABLE ` + locationDefaultPartition + ` IN EXCLUSIVE MODE`,
+		fmt.Sprintf(`CREATE TABLE %s (LIKE locations INCLUDING DEFAULTS)`, p.Name),
+		fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s WHERE timestamp >= '%s' AND timestamp < '%s'`, p.Name, locationDefaultPartition, from, to),
+		fmt.Sprintf(`DELETE FROM %s WHERE timestamp >= '%s' AND timestamp < '%s'`, locationDefaultPartition, from, to),
+		fmt.Sprintf(`ALTER TABLE locations ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`, p.Name, from, to),
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return p, false, err
+		}
+	}
+	return p, true, tx.Commit()
+}
+
+// DropLocationPartition drops a monthly partition with its points and the
+// client point claims of the month. Deliveries of its points in streams
+// keep their row with a null location ID.
+func DropLocationPartition(ctx context.Context, p LocationPartition) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM location_client_points WHERE timestamp >= $1 AND timestamp < $2`, p.From, p.To); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE streams SET location_id = NULL WHERE location_id IN (SELECT id FROM `+p.Name+`)`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE `+p.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
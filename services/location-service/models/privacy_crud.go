@@ -0,0 +1,118 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/privacy"
+)
+
+var ErrPrivacyPolicyNotFound = errors.New("privacy policy not found")
+
+const privacyPolicyColumns = `tenant_id, user_id, coordinate_precision, working_hours, outside_hours, blur_precision, updated_at`
+
+func scanPrivacyPolicy(row rowScanner) (*privacy.Policy, error) {
+	p := &privacy.Policy{}
+	var precision sql.NullInt64
+	var workingHours []byte
+	if err := row.Scan(&p.TenantID, &p.UserID, &precision, &workingHours, &p.OutsideHours, &p.BlurPrecision, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if precision.Valid {
+		n := int(precision.Int64)
+		p.Precision = &n
+	}
+	if len(workingHours) > 0 {
+		if err := json.Unmarshal(workingHours, &p.WorkingHours); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// GetPrivacyPolicy returns the policy of a tenant, or of one of its users
+// when userID is set, or ErrPrivacyPolicyNotFound if there is none
+func GetPrivacyPolicy(ctx context.Context, tenantID, userID string) (*privacy.Policy, error) {
+	p, err := scanPrivacyPolicy(DB.QueryRowContext(ctx, `SELECT `+privacyPolicyColumns+` FROM privacy_policies
+		WHERE tenant_id = $1 AND user_id = $2`, tenantID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPrivacyPolicyNotFound
+	}
+	return p, err
+}
+
+// EffectivePrivacyPolicy returns the policy that applies to a tenant user:
+// their own, else the tenant's, else nil
+func EffectivePrivacyPolicy(ctx context.Context, tenantID, userID string) (*privacy.Policy, error) {
+	p, err := scanPrivacyPolicy(DB.QueryRowContext(ctx, `SELECT `+privacyPolicyColumns+` FROM privacy_policies
+		WHERE tenant_id = $1 AND user_id IN ($2, '') ORDER BY user_id DESC LIMIT 1`, tenantID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// GetTenantPrivacyPolicies returns the policy of a tenant together with
+// those of its users
+func GetTenantPrivacyPolicies(ctx context.Context, tenantID string) (*privacy.TenantPolicies, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT `+privacyPolicyColumns+` FROM privacy_policies WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tp := &privacy.TenantPolicies{TenantID: tenantID, Users: map[string]*privacy.Policy{}}
+	for rows.Next() {
+		p, err := scanPrivacyPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		if p.UserID == "" {
+			tp.Tenant = p
+		} else {
+			tp.Users[p.UserID] = p
+		}
+	}
+	return tp, rows.Err()
+}
+
+// SavePrivacyPolicy creates or replaces the policy of a tenant or user
+func SavePrivacyPolicy(ctx context.Context, p *privacy.Policy) error {
+	var workingHours interface{}
+	if p.WorkingHours != nil {
+		b, err := json.Marshal(p.WorkingHours)
+		if err != nil {
+			return err
+		}
+		workingHours = string(b)
+	}
+	var precision interface{}
+	if p.Precision != nil {
+		precision = *p.Precision
+	}
+	p.UpdatedAt = time.Now()
+	_, err := DB.ExecContext(ctx, `INSERT INTO privacy_policies (`+privacyPolicyColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE SET coordinate_precision = EXCLUDED.coordinate_precision, working_hours = EXCLUDED.working_hours,
+			outside_hours = EXCLUDED.outside_hours, blur_precision = EXCLUDED.blur_precision, updated_at = EXCLUDED.updated_at`,
+		p.TenantID, p.UserID, precision, workingHours, p.OutsideHours, p.BlurPrecision, p.UpdatedAt)
+	return err
+}
+
+// DeletePrivacyPolicy removes the policy of a tenant or user, or returns
+// ErrPrivacyPolicyNotFound if there is none
+func DeletePrivacyPolicy(ctx context.Context, tenantID, userID string) error {
+	res, err := DB.ExecContext(ctx, `DELETE FROM privacy_policies WHERE tenant_id = $1 AND user_id = $2`, tenantID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrPrivacyPolicyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+)
+
+// MaxRetentionDays is the longest retention a tenant may choose
+const MaxRetentionDays = 36500
+
+// RetentionSettings is how long a tenant keeps its location history.
+// RetentionDays 0 keeps it forever.
+type RetentionSettings struct {
+	TenantID      string    `json:"tenant_id"`
+	RetentionDays int       `json:"retention_days"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty"`
+}
+
+func (s *RetentionSettings) Validate(v *validation.Validator) {
+	if s.RetentionDays < 0 || s.RetentionDays > MaxRetentionDays {
+		v.Add("retention_days", apierror.FieldOutOfRange, fmt.Sprintf("retention_days must be between 0 and %d", MaxRetentionDays))
+	}
+}
+
+// Cutoff returns the time before which points of the tenant expire, or
+// the zero time when they are kept forever
+func (s *RetentionSettings) Cutoff(now time.Time) time.Time {
+	if s.RetentionDays == 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -s.RetentionDays)
+}
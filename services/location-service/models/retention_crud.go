@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrRetentionSettingsNotFound = errors.New("retention settings not found")
+
+// GetRetentionSettings returns the retention of a tenant, or
+// ErrRetentionSettingsNotFound if the tenant never chose one
+func GetRetentionSettings(ctx context.Context, tenantID string) (*RetentionSettings, error) {
+	s := &RetentionSettings{TenantID: tenantID}
+	err := DB.QueryRowContext(ctx, `SELECT retention_days, updated_at FROM retention_settings WHERE tenant_id = $1`, tenantID).
+		Scan(&s.RetentionDays, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRetentionSettingsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListRetentionSettings returns the retention of every tenant that chose one
+func ListRetentionSettings(ctx context.Context) ([]RetentionSettings, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT tenant_id, retention_days, updated_at FROM retention_settings ORDER BY tenant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var settings []RetentionSettings
+	for rows.Next() {
+		var s RetentionSettings
+		if err := rows.Scan(&s.TenantID, &s.RetentionDays, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+// SaveRetentionSettings creates or replaces the retention of a tenant
+func SaveRetentionSettings(ctx context.Context, s *RetentionSettings) error {
+	s.UpdatedAt = time.Now()
+	_, err := DB.ExecContext(ctx, `INSERT INTO retention_settings (tenant_id, retention_days, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE SET retention_days = EXCLUDED.retention_days, updated_at = EXCLUDED.updated_at`,
+		s.TenantID, s.RetentionDays, s.UpdatedAt)
+	return err
+}
+
+// DeleteExpiredLocations deletes up to limit points of a tenant older than
+// before, with their client point claims, and returns the number deleted
+func DeleteExpiredLocations(ctx context.Context, tenantID string, before time.Time, limit int) (int64, error) {
+	return deleteLocationsBatch(ctx, `tenant_id = $1`, tenantID, before, limit)
+}
+
+// DeleteExpiredLocationsExcept deletes up to limit points older than before
+// of every tenant but the given ones, and returns the number deleted
+func DeleteExpiredLocationsExcept(ctx context.Context, tenantIDs []string, before time.Time, limit int) (int64, error) {
+	return deleteLocationsBatch(ctx, `tenant_id <> ALL($1)`, pq.Array(tenantIDs), before, limit)
+}
+
+func deleteLocationsBatch(ctx context.Context, tenantFilter string, tenantArg interface{}, before time.Time, limit int) (int64, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// streams.location_id has no foreign key on the partitioned table, so
+	// the deliveries of the deleted points are cleared here
+	var deleted int64
+	if err := tx.QueryRowContext(ctx, `WITH deleted AS (
+			DELETE FROM locations WHERE (id, timestamp) IN (
+				SELECT id, timestamp FROM locations WHERE `+tenantFilter+` AND timestamp < $2 LIMIT $3)
+			RETURNING id),
+		cleared AS (UPDATE streams SET location_id = NULL WHERE location_id IN (SELECT id FROM deleted))
+		SELECT COUNT(*) FROM deleted`,
+		tenantArg, before, limit).Scan(&deleted); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM location_client_points WHERE (tenant_id, user_id, client_point_id) IN (
+		SELECT tenant_id, user_id, client_point_id FROM location_client_points WHERE `+tenantFilter+` AND timestamp < $2 LIMIT $3)`,
+		tenantArg, before, limit); err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	SessionStatusActive  = "active"
+	SessionStatusEnded   = "ended"
+	SessionStatusExpired = "expired"
+)
+
+// Session is an explicit location tracking session started by a tenant user.
+// Locations may only be submitted while the session is active.
+type Session struct {
+	ID        string     `json:"id"`
+	TenantID  string     `json:"tenant_id"`
+	UserID    string     `json:"user_id"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Status    string     `json:"status"`
+}
+
+func NewSession(tenantID, userID string, startedAt time.Time, duration time.Duration) *Session {
+	return &Session{
+		ID:        newID(),
+		TenantID:  tenantID,
+		UserID:    userID,
+		StartedAt: startedAt,
+		ExpiresAt: startedAt.Add(duration),
+		Status:    SessionStatusActive,
+	}
+}
+
+// StatusAt returns the session status at the given time
+func (s *Session) StatusAt(t time.Time) string {
+	if s.EndedAt != nil {
+		return SessionStatusEnded
+	}
+	if !t.Before(s.ExpiresAt) {
+		return SessionStatusExpired
+	}
+	return SessionStatusActive
+}
+
+// Covers reports whether t falls inside the session window
+func (s *Session) Covers(t time.Time) bool {
+	end := s.ExpiresAt
+	if s.EndedAt != nil && s.EndedAt.Before(end) {
+		end = *s.EndedAt
+	}
+	return !t.Before(s.StartedAt) && !t.After(end)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+func SaveSession(ctx context.Context, s *Session) error {
+	_, err := DB.ExecContext(ctx, `INSERT INTO sessions (id, tenant_id, user_id, started_at, expires_at) VALUES ($1, $2, $3, $4, $5)`, s.ID, s.TenantID, s.UserID, s.StartedAt, s.ExpiresAt)
+	return err
+}
+
+const sessionColumns = `id, tenant_id, user_id, started_at, expires_at, ended_at`
+
+func scanSession(row rowScanner) (*Session, error) {
+	var s Session
+	var endedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.TenantID, &s.UserID, &s.StartedAt, &s.ExpiresAt, &endedAt); err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		s.EndedAt = &endedAt.Time
+	}
+	s.Status = s.StatusAt(time.Now())
+	return &s, nil
+}
+
+// GetSession returns a session belonging to tenantID
+func GetSession(ctx context.Context, tenantID, id string) (*Session, error) {
+	s, err := scanSession(DB.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = $1 AND tenant_id = $2`, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	return s, err
+}
+
+// ListUserSessions returns the sessions of a tenant user, oldest first
+func ListUserSessions(ctx context.Context, tenantID, userID string) ([]Session, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE tenant_id = $1 AND user_id = $2
+		ORDER BY started_at, id`, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := []Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *s)
+	}
+	return sessions, rows.Err()
+}
+
+// EndSession marks an active session as ended. Ending a session twice keeps
+// the original end time.
+func EndSession(ctx context.Context, tenantID, id string, endedAt time.Time) (*Session, error) {
+	_, err := DB.ExecContext(ctx, `UPDATE sessions SET ended_at = LEAST($3, expires_at) WHERE id = $1 AND tenant_id = $2 AND ended_at IS NULL`, id, tenantID, endedAt)
+	if err != nil {
+		return nil, err
+	}
+	return GetSession(ctx, tenantID, id)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PostGIS reports whether the locations and geofences tables have the
+// geography columns added when the postgis extension is available. Without
+// them spatial queries fall back to plain coordinate columns and Go.
+var PostGIS bool
+
+// detectPostGIS sets PostGIS from the database schema
+func detectPostGIS(ctx context.Context) error {
+	return DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'locations' AND column_name = 'geog')`).Scan(&PostGIS)
+}
+
+// pointGeography returns the SQL building a geography point from the
+// longitude and latitude expressions lon and lat
+func pointGeography(lon, lat string) string {
+	return fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s, %s), 4326)::geography", lon, lat)
+}
+
+// shapeGeography returns the SQL building the geography of a WKT shape.
+// Edges are densified to 0.01 degrees so they follow the planar edges the
+// service tests points against, within centimeters.
+func shapeGeography(wkt string) string {
+	return fmt.Sprintf("ST_Segmentize(ST_GeomFromText(%s, 4326), 0.01)::geography", wkt)
+}
+
+// spatialBBoxMaxDegrees is the widest bounding box filtered with the
+// geography index; larger boxes use the coordinate index
+const spatialBBoxMaxDegrees = 10.0
+
+// bboxGeography returns the SQL building the geography of b, with its
+// corners at the given placeholders, or false when b is too large or
+// crosses the antimeridian
+func bboxGeography(b *BoundingBox, minLon, minLat, maxLon, maxLat string) (string, bool) {
+	if b.MinLon > b.MaxLon || b.MaxLon-b.MinLon > spatialBBoxMaxDegrees || b.MaxLat-b.MinLat > spatialBBoxMaxDegrees {
+		return "", false
+	}
+	return fmt.Sprintf("ST_Segmentize(ST_MakeEnvelope(%s, %s, %s, %s, 4326), 0.01)::geography", minLon, minLat, maxLon, maxLat), true
+}
+
+// geofenceWKT returns the shape of g as WKT: the center of a circle or the
+// closed ring of a polygon
+func geofenceWKT(g *Geofence) string {
+	if g.Type == GeofenceTypeCircle && g.Center != nil {
+		return "POINT(" + wktCoordinate(g.Center.Longitude, g.Center.Latitude) + ")"
+	}
+	ring := append([]Coordinate(nil), g.Polygon...)
+	if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+		ring = append(ring, ring[0])
+	}
+	coords := make([]string, len(ring))
+	for i, c := range ring {
+		coords[i] = wktCoordinate(c.Longitude, c.Latitude)
+	}
+	return "POLYGON((" + strings.Join(coords, ", ") + "))"
+}
+
+func wktCoordinate(lon, lat float64) string {
+	return strconv.FormatFloat(lon, 'f', -1, 64) + " " + strconv.FormatFloat(lat, 'f', -1, 64)
+}
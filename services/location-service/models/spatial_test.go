@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withSealing enables encryption with a cached data key, so locations are
+// sealed without a database
+func withSealing(t *testing.T, tenantID string) {
+	t.Helper()
+	keyring = &Keyring{
+		currentTTL: time.Hour,
+		keys:       map[dataKeyID][]byte{{tenantID, 1}: make([]byte, 32)},
+		current:    map[string]currentDataKey{tenantID: {version: 1, fetchedAt: time.Now()}},
+	}
+	PostGIS = true
+	t.Cleanup(func() {
+		keyring = nil
+		PostGIS = false
+	})
+}
+
+func TestLocationInsertRowGeography(t *testing.T) {
+	l := &Location{TenantID: "acme", UserID: "u-1", Latitude: 45.5, Longitude: 7.25, Timestamp: time.Now()}
+
+	PostGIS = true
+	defer func() { PostGIS = false }()
+	values, args, err := locationInsertRow(context.Background(), l, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(values, pointGeography("$2", "$1")+")") {
+		t.Errorf("plain row %s has no geography of its coordinates", values)
+	}
+	if args[0] != 45.5 || args[1] != 7.25 {
+		t.Errorf("plain row coordinates = %v, %v", args[0], args[1])
+	}
+
+	withSealing(t, "acme")
+	values, args, err = locationInsertRow(context.Background(), l, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(values, ", NULL)") {
+		t.Errorf("sealed row %s stores a geography", values)
+	}
+	if args[0] != nil || args[1] != nil {
+		t.Errorf("sealed row stores coordinates %v, %v", args[0], args[1])
+	}
+	if args[len(args)-1] == nil {
+		t.Error("sealed row has no ciphertext")
+	}
+}
+
+func TestLocationQueryBBoxKeepsSealed(t *testing.T) {
+	q := LocationQuery{TenantID: "acme", BBox: &BoundingBox{MinLat: 45, MinLon: 7, MaxLat: 46, MaxLon: 8}}
+
+	PostGIS = true
+	defer func() { PostGIS = false }()
+	conditions, _ := q.where()
+	if strings.Contains(strings.Join(conditions, " AND "), "ciphertext") {
+		t.Errorf("conditions %v admit sealed locations without encryption", conditions)
+	}
+
+	withSealing(t, "acme")
+	conditions, _ = q.where()
+	box := conditions[len(conditions)-1]
+	if !strings.HasPrefix(box, "(ciphertext IS NOT NULL OR (") || !strings.Contains(box, "geog &&") {
+		t.Errorf("bbox condition %s drops sealed locations", box)
+	}
+}
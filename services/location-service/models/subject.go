@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Erasure statuses. An erasure is pending from the moment the data of the
+// user is deleted until the tombstones have been published.
+const (
+	ErasureStatusPending   = "pending"
+	ErasureStatusCompleted = "completed"
+)
+
+// UserProfile is the account of a tenant user in the users table, matched
+// by numeric ID or username against the user ID of their locations
+type UserProfile struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery records the delivery of a location of a user to a third party
+type Delivery struct {
+	ID         int64     `json:"id"`
+	LocationID *int64    `json:"location_id,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	StreamedAt time.Time `json:"streamed_at"`
+}
+
+// ErasureCertificate records the erasure of everything held about a tenant
+// user: who asked for it, how many rows were deleted from each table and
+// the Kafka topics the tombstones went to. It is kept after the data is
+// gone, as evidence that the request was carried out.
+type ErasureCertificate struct {
+	ID              string           `json:"id"`
+	TenantID        string           `json:"tenant_id"`
+	UserID          string           `json:"user_id"`
+	RequestedBy     string           `json:"requested_by"`
+	Status          string           `json:"status"`
+	Deleted         map[string]int64 `json:"deleted"`
+	TombstoneTopics []string         `json:"tombstone_topics"`
+	RequestedAt     time.Time        `json:"requested_at"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,241 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrErasureNotFound = errors.New("erasure not found")
+
+// userProfileQuery selects the account of a tenant user; $1 is the tenant
+// and $2 the user ID
+const userProfileQuery = `SELECT u.id, u.username, u.email, u.role, u.created_at FROM users u
+	JOIN tenants t ON t.id = u.tenant_id
+	WHERE t.tenant_id = $1 AND (u.id::text = $2 OR u.username = $2)
+	ORDER BY u.id LIMIT 1`
+
+func scanUserProfile(row rowScanner) (*UserProfile, error) {
+	var u UserProfile
+	var createdAt sql.NullTime
+	if err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &createdAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	u.CreatedAt = createdAt.Time
+	return &u, nil
+}
+
+// GetUserProfile returns the account of a tenant user, or nil when the
+// user has none
+func GetUserProfile(ctx context.Context, tenantID, userID string) (*UserProfile, error) {
+	return scanUserProfile(DB.QueryRowContext(ctx, userProfileQuery, tenantID, userID))
+}
+
+// ListUserDeliveries returns the third-party deliveries recorded for an
+// account, oldest first
+func ListUserDeliveries(ctx context.Context, profileID int64) ([]Delivery, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT id, location_id, COALESCE(thirdparty_status, ''), streamed_at FROM streams
+		WHERE user_id = $1 ORDER BY streamed_at, id`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		var locationID sql.NullInt64
+		var streamedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &locationID, &d.Status, &streamedAt); err != nil {
+			return nil, err
+		}
+		if locationID.Valid {
+			d.LocationID = &locationID.Int64
+		}
+		d.StreamedAt = streamedAt.Time
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// userLockScopes are the scopes of WithUserLock under which the trips and
+// activity rollups of a user are updated on ingest
+var userLockScopes = []string{"trips", "activity"}
+
+// userTables are the tables holding rows of a tenant user, in the order
+// they are erased; $1 is the tenant and $2 the user ID. Heatmap rollups
+// are counts per tenant and are not attributed to users.
+var userTables = []struct {
+	name  string
+	query string
+}{
+	{"submission_limits", `DELETE FROM submission_limits WHERE key IN (SELECT id FROM sessions WHERE tenant_id = $1 AND user_id = $2)`},
+	{"location_outbox", `DELETE FROM location_outbox WHERE tenant_id = $1 AND user_id = $2`},
+	{"locations", `DELETE FROM locations WHERE tenant_id = $1 AND user_id = $2`},
+	{"location_client_points", `DELETE FROM location_client_points WHERE tenant_id = $1 AND user_id = $2`},
+	{"sessions", `DELETE FROM sessions WHERE tenant_id = $1 AND user_id = $2`},
+	{"import_jobs", `DELETE FROM import_jobs WHERE tenant_id = $1 AND user_id = $2`},
+	{"geofence_states", `DELETE FROM geofence_states WHERE tenant_id = $1 AND user_id = $2`},
+	{"geofence_events", `DELETE FROM geofence_events WHERE tenant_id = $1 AND user_id = $2`},
+	{"trips", `DELETE FROM trips WHERE tenant_id = $1 AND user_id = $2`},
+	{"stops", `DELETE FROM stops WHERE tenant_id = $1 AND user_id = $2`},
+	{"trip_segmenter_states", `DELETE FROM trip_segmenter_states WHERE tenant_id = $1 AND user_id = $2`},
+	{"activity_hourly", `DELETE FROM activity_hourly WHERE tenant_id = $1 AND user_id = $2`},
+	{"activity_daily", `DELETE FROM activity_daily WHERE tenant_id = $1 AND user_id = $2`},
+	{"privacy_policies", `DELETE FROM privacy_policies WHERE tenant_id = $1 AND user_id = $2`},
+}
+
+// EraseUser deletes everything stored about a tenant user, including their
+// account and its third-party deliveries, in one transaction, and records
+// a pending certificate of it. A pending erasure of the same user, left by
+// a request that failed to publish its tombstones, is continued rather
+// than a new one started. Points the user submits afterwards are stored
+// again.
+func EraseUser(ctx context.Context, tenantID, userID, requestedBy string) (*ErasureCertificate, error) {
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	// Keep trip segmentation and activity rollups from writing the user
+	// back while the erasure runs
+	for _, scope := range userLockScopes {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, scope+":"+tenantID+"/"+userID); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := scanErasure(tx.QueryRowContext(ctx, `SELECT `+erasureColumns+` FROM user_erasures
+		WHERE tenant_id = $1 AND user_id = $2 AND status = $3 ORDER BY requested_at LIMIT 1 FOR UPDATE`,
+		tenantID, userID, ErasureStatusPending))
+	if errors.Is(err, sql.ErrNoRows) {
+		c, err = &ErasureCertificate{
+			ID:          newID(),
+			TenantID:    tenantID,
+			UserID:      userID,
+			RequestedBy: requestedBy,
+			Status:      ErasureStatusPending,
+			Deleted:     map[string]int64{},
+			RequestedAt: time.Now(),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	add := func(table string, res sql.Result) error {
+		n, err := res.RowsAffected()
+		c.Deleted[table] += n
+		return err
+	}
+	for _, t := range userTables {
+		res, err := tx.ExecContext(ctx, t.query, tenantID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(t.name, res); err != nil {
+			return nil, err
+		}
+	}
+	profile, err := scanUserProfile(tx.QueryRowContext(ctx, userProfileQuery+` FOR UPDATE OF u`, tenantID, userID))
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil {
+		res, err := tx.ExecContext(ctx, `DELETE FROM streams WHERE user_id = $1`, profile.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := add("streams", res); err != nil {
+			return nil, err
+		}
+		if res, err = tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, profile.ID); err != nil {
+			return nil, err
+		}
+		if err := add("users", res); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted, err := json.Marshal(c.Deleted)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO user_erasures (id, tenant_id, user_id, requested_by, status, deleted, requested_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO UPDATE SET deleted = EXCLUDED.deleted`,
+		c.ID, c.TenantID, c.UserID, c.RequestedBy, c.Status, string(deleted), c.RequestedAt); err != nil {
+		return nil, err
+	}
+	return c, tx.Commit()
+}
+
+// CompleteErasure marks an erasure completed once its tombstones have been
+// published to topics
+func CompleteErasure(ctx context.Context, c *ErasureCertificate, topics []string) error {
+	completedAt := time.Now()
+	if _, err := DB.ExecContext(ctx, `UPDATE user_erasures SET status = $2, tombstone_topics = $3, completed_at = $4 WHERE id = $1`,
+		c.ID, ErasureStatusCompleted, pq.Array(topics), completedAt); err != nil {
+		return err
+	}
+	c.Status, c.TombstoneTopics, c.CompletedAt = ErasureStatusCompleted, topics, &completedAt
+	return nil
+}
+
+const erasureColumns = `id, tenant_id, user_id, requested_by, status, deleted, tombstone_topics, requested_at, completed_at`
+
+func scanErasure(row rowScanner) (*ErasureCertificate, error) {
+	var c ErasureCertificate
+	var deleted []byte
+	var completedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.TenantID, &c.UserID, &c.RequestedBy, &c.Status, &deleted, pq.Array(&c.TombstoneTopics),
+		&c.RequestedAt, &completedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(deleted, &c.Deleted); err != nil {
+		return nil, err
+	}
+	if c.TombstoneTopics == nil {
+		c.TombstoneTopics = []string{}
+	}
+	if completedAt.Valid {
+		c.CompletedAt = &completedAt.Time
+	}
+	return &c, nil
+}
+
+// GetErasure returns an erasure certificate belonging to tenantID
+func GetErasure(ctx context.Context, tenantID, id string) (*ErasureCertificate, error) {
+	c, err := scanErasure(DB.QueryRowContext(ctx, `SELECT `+erasureColumns+` FROM user_erasures WHERE id = $1 AND tenant_id = $2`, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrErasureNotFound
+	}
+	return c, err
+}
+
+// ListErasuresSince returns the erasures requested at or after since, of
+// every tenant, so replicas can drop the users from their caches
+func ListErasuresSince(ctx context.Context, since time.Time) ([]ErasureCertificate, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT `+erasureColumns+` FROM user_erasures WHERE requested_at >= $1 ORDER BY requested_at`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var erasures []ErasureCertificate
+	for rows.Next() {
+		c, err := scanErasure(rows)
+		if err != nil {
+			return nil, err
+		}
+		erasures = append(erasures, *c)
+	}
+	return erasures, rows.Err()
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
+)
+
+const (
+	ProviderGPS     = "gps"
+	ProviderNetwork = "network"
+	ProviderFused   = "fused"
+)
+
+// Accepted ranges of the telemetry fields
+const (
+	MaxAccuracyMeters   = 100000.0
+	MinAltitudeMeters   = -1000.0
+	MaxAltitudeMeters   = 20000.0
+	MaxSpeedMetersPerS  = 350.0
+	MaxBatteryLevel     = 100.0
+	MaxMetadataEntries  = 32
+	MaxMetadataKeyLen   = 64
+	MaxMetadataValueLen = 256
+)
+
+// Telemetry holds the optional readings a device reports with a position.
+// Unset readings are nil.
+type Telemetry struct {
+	Accuracy     *float64          `json:"accuracy,omitempty"`      // Horizontal accuracy radius in meters
+	Altitude     *float64          `json:"altitude,omitempty"`      // Meters above sea level
+	Speed        *float64          `json:"speed,omitempty"`         // Meters per second
+	Heading      *float64          `json:"heading,omitempty"`       // Degrees clockwise from true north, [0, 360)
+	BatteryLevel *float64          `json:"battery_level,omitempty"` // Percent, [0, 100]
+	Provider     string            `json:"provider,omitempty"`      // gps, network or fused
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// Validate checks every reported field against its accepted range
+func (t *Telemetry) Validate(v *validation.Validator) {
+	v.Range("accuracy", t.Accuracy, 0, MaxAccuracyMeters)
+	v.Range("altitude", t.Altitude, MinAltitudeMeters, MaxAltitudeMeters)
+	v.Range("speed", t.Speed, 0, MaxSpeedMetersPerS)
+	if t.Heading != nil && *t.Heading == 360 {
+		v.Add("heading", apierror.FieldOutOfRange, "heading must be less than 360")
+	} else {
+		v.Range("heading", t.Heading, 0, 360)
+	}
+	v.Range("battery_level", t.BatteryLevel, 0, MaxBatteryLevel)
+	v.OneOf("provider", t.Provider, ProviderGPS, ProviderNetwork, ProviderFused)
+	if len(t.Metadata) > MaxMetadataEntries {
+		v.Add("metadata", apierror.FieldTooLong, fmt.Sprintf("metadata exceeds %d entries", MaxMetadataEntries))
+	}
+	keys := make([]string, 0, len(t.Metadata))
+	for k := range t.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if k == "" {
+			v.Add("metadata", apierror.FieldInvalid, "metadata keys must not be empty")
+		}
+		v.MaxLength("metadata."+k, k, MaxMetadataKeyLen)
+		v.MaxLength("metadata."+k, t.Metadata[k], MaxMetadataValueLen)
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+)
+
+// Trip is a movement of a user between two stops, or between the start or
+// end of their data and a stop
+type Trip struct {
+	ID             int64      `json:"id"`
+	TenantID       string     `json:"tenant_id"`
+	UserID         string     `json:"user_id"`
+	StartedAt      time.Time  `json:"started_at"`
+	EndedAt        time.Time  `json:"ended_at"`
+	Start          Coordinate `json:"start"`
+	End            Coordinate `json:"end"`
+	DurationSecs   int64      `json:"duration_s"`
+	DistanceMeters float64    `json:"distance_m"`
+	MaxSpeed       float64    `json:"max_speed_mps"`
+	PointCount     int        `json:"point_count"`
+	Polyline       string     `json:"polyline"` // Encoded polyline, precision 5
+}
+
+// Stop is a stay of a user within a small radius. Location is the mean
+// position of its points.
+type Stop struct {
+	ID           int64      `json:"id"`
+	TenantID     string     `json:"tenant_id"`
+	UserID       string     `json:"user_id"`
+	StartedAt    time.Time  `json:"started_at"`
+	EndedAt      time.Time  `json:"ended_at"`
+	Location     Coordinate `json:"location"`
+	DurationSecs int64      `json:"duration_s"`
+	PointCount   int        `json:"point_count"`
+}
+
+func durationSecs(from, to time.Time) int64 {
+	return int64(to.Sub(from) / time.Second)
+}
+
+// SegmentQuery filters the trips or stops of a tenant by start time.
+// Results are ordered by start time, then ID.
+type SegmentQuery struct {
+	pagination.Params
+	TenantID string
+	UserID   string
+	From     *time.Time
+	To       *time.Time
+}
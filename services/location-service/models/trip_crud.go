@@ -0,0 +1,206 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+)
+
+// SaveTrip stores a completed trip as part of tx
+func SaveTrip(ctx context.Context, tx *sql.Tx, t *Trip) error {
+	return tx.QueryRowContext(ctx, `INSERT INTO trips (tenant_id, user_id, started_at, ended_at, start_latitude, start_longitude,
+		end_latitude, end_longitude, distance_m, max_speed_mps, point_count, polyline)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		t.TenantID, t.UserID, t.StartedAt, t.EndedAt, t.Start.Latitude, t.Start.Longitude,
+		t.End.Latitude, t.End.Longitude, t.DistanceMeters, t.MaxSpeed, t.PointCount, t.Polyline).Scan(&t.ID)
+}
+
+// SaveStop stores a completed stop as part of tx
+func SaveStop(ctx context.Context, tx *sql.Tx, s *Stop) error {
+	return tx.QueryRowContext(ctx, `INSERT INTO stops (tenant_id, user_id, started_at, ended_at, latitude, longitude, point_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		s.TenantID, s.UserID, s.StartedAt, s.EndedAt, s.Location.Latitude, s.Location.Longitude, s.PointCount).Scan(&s.ID)
+}
+
+// TripSegmenterState is the stored progress of a user's segmentation.
+// BackfillAt is set while a backfill rebuilds the user's trips and stops.
+type TripSegmenterState struct {
+	State      []byte
+	BackfillAt *time.Time
+}
+
+// GetTripSegmenterState returns the state of a tenant user, or an empty one
+func GetTripSegmenterState(ctx context.Context, tx *sql.Tx, tenantID, userID string) (TripSegmenterState, error) {
+	var s TripSegmenterState
+	var backfillAt sql.NullTime
+	err := tx.QueryRowContext(ctx, `SELECT state, backfill_at FROM trip_segmenter_states WHERE tenant_id = $1 AND user_id = $2`,
+		tenantID, userID).Scan(&s.State, &backfillAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, nil
+	}
+	if backfillAt.Valid {
+		s.BackfillAt = &backfillAt.Time
+	}
+	return s, err
+}
+
+func SaveTripSegmenterState(ctx context.Context, tx *sql.Tx, tenantID, userID string, s TripSegmenterState) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO trip_segmenter_states (tenant_id, user_id, state, backfill_at, updated_at) VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (tenant_id, user_id) DO UPDATE SET state = EXCLUDED.state, backfill_at = EXCLUDED.backfill_at, updated_at = NOW()`,
+		tenantID, userID, string(s.State), s.BackfillAt)
+	return err
+}
+
+// DeleteTripsAndStops removes the trips and stops of a tenant user
+func DeleteTripsAndStops(ctx context.Context, tx *sql.Tx, tenantID, userID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM trips WHERE tenant_id = $1 AND user_id = $2`, tenantID, userID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM stops WHERE tenant_id = $1 AND user_id = $2`, tenantID, userID)
+	return err
+}
+
+// ListLocationUsers returns the IDs of the users with stored locations in a
+// tenant
+func ListLocationUsers(ctx context.Context, tenantID string) ([]string, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT DISTINCT user_id FROM locations WHERE tenant_id = $1 AND user_id IS NOT NULL ORDER BY user_id`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// where returns the conditions and arguments selecting q, including the
+// cursor
+func (q *SegmentQuery) where() ([]string, []interface{}, error) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{q.TenantID}
+	add := func(cond string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+	if q.UserID != "" {
+		add("user_id = $%d", q.UserID)
+	}
+	if q.From != nil {
+		add("started_at >= $%d", *q.From)
+	}
+	if q.To != nil {
+		add("started_at < $%d", *q.To)
+	}
+	if q.Cursor != nil {
+		ts, err := time.Parse(time.RFC3339Nano, q.Cursor.Value)
+		if err != nil {
+			return nil, nil, pagination.ErrInvalidCursor
+		}
+		id, err := strconv.ParseInt(q.Cursor.ID, 10, 64)
+		if err != nil {
+			return nil, nil, pagination.ErrInvalidCursor
+		}
+		args = append(args, ts, id)
+		conditions = append(conditions, fmt.Sprintf("(started_at, id) %s ($%d, $%d)", q.Comparator(), len(args)-1, len(args)))
+	}
+	return conditions, args, nil
+}
+
+// QueryTrips returns one page of the trips matching q
+func QueryTrips(ctx context.Context, q SegmentQuery) (*pagination.Page[Trip], error) {
+	if q.Limit <= 0 {
+		q.Limit = pagination.DefaultLimit
+	}
+	conditions, args, err := q.where()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, q.Limit+1)
+	rows, err := DB.QueryContext(ctx, fmt.Sprintf(`SELECT id, tenant_id, user_id, started_at, ended_at, start_latitude, start_longitude,
+		end_latitude, end_longitude, distance_m, max_speed_mps, point_count, polyline
+		FROM trips WHERE %s ORDER BY started_at %s, id %s LIMIT $%d`,
+		strings.Join(conditions, " AND "), q.Direction(), q.Direction(), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	trips := []Trip{}
+	for rows.Next() {
+		var t Trip
+		if err := rows.Scan(&t.ID, &t.TenantID, &t.UserID, &t.StartedAt, &t.EndedAt, &t.Start.Latitude, &t.Start.Longitude,
+			&t.End.Latitude, &t.End.Longitude, &t.DistanceMeters, &t.MaxSpeed, &t.PointCount, &t.Polyline); err != nil {
+			return nil, err
+		}
+		t.DurationSecs = durationSecs(t.StartedAt, t.EndedAt)
+		trips = append(trips, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &pagination.Page[Trip]{Items: trips}
+	if len(trips) > q.Limit {
+		page.Items = trips[:q.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = segmentCursor(last.StartedAt, last.ID)
+	}
+	return page, nil
+}
+
+// QueryStops returns one page of the stops matching q
+func QueryStops(ctx context.Context, q SegmentQuery) (*pagination.Page[Stop], error) {
+	if q.Limit <= 0 {
+		q.Limit = pagination.DefaultLimit
+	}
+	conditions, args, err := q.where()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, q.Limit+1)
+	rows, err := DB.QueryContext(ctx, fmt.Sprintf(`SELECT id, tenant_id, user_id, started_at, ended_at, latitude, longitude, point_count
+		FROM stops WHERE %s ORDER BY started_at %s, id %s LIMIT $%d`,
+		strings.Join(conditions, " AND "), q.Direction(), q.Direction(), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stops := []Stop{}
+	for rows.Next() {
+		var s Stop
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.UserID, &s.StartedAt, &s.EndedAt, &s.Location.Latitude, &s.Location.Longitude, &s.PointCount); err != nil {
+			return nil, err
+		}
+		s.DurationSecs = durationSecs(s.StartedAt, s.EndedAt)
+		stops = append(stops, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &pagination.Page[Stop]{Items: stops}
+	if len(stops) > q.Limit {
+		page.Items = stops[:q.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = segmentCursor(last.StartedAt, last.ID)
+	}
+	return page, nil
+}
+
+func segmentCursor(startedAt time.Time, id int64) string {
+	return pagination.EncodeCursor(pagination.Cursor{
+		Value: startedAt.Format(time.RFC3339Nano),
+		ID:    strconv.FormatInt(id, 10),
+	})
+}
@@ -0,0 +1,152 @@
+// Package mvt encodes Mapbox Vector Tiles (specification 2.1) holding point
+// features with integer properties.
+package mvt
+
+import "sort"
+
+// ContentType is the media type of encoded tiles
+const ContentType = "application/vnd.mapbox-vector-tile"
+
+// DefaultExtent is the width and height of a tile in layer coordinates
+const DefaultExtent = 4096
+
+// Layer is a named set of features sharing one coordinate extent
+type Layer struct {
+	Name     string
+	Extent   uint32
+	Features []Feature
+}
+
+// Feature is a point in layer coordinates, with (0, 0) at the top left of
+// the tile
+type Feature struct {
+	X          int32
+	Y          int32
+	Properties map[string]int64
+}
+
+// Protobuf field numbers of the vector tile schema
+const (
+	tileLayers = 3
+
+	layerVersion  = 15
+	layerName     = 1
+	layerFeatures = 2
+	layerKeys     = 3
+	layerValues   = 4
+	layerExtent   = 5
+
+	featureTags     = 2
+	featureType     = 3
+	featureGeometry = 4
+
+	valueInt = 4
+
+	geomTypePoint = 1
+	cmdMoveTo     = 1
+)
+
+// Encode returns the protobuf encoding of a tile made of layers
+func Encode(layers ...Layer) []byte {
+	var tile []byte
+	for _, l := range layers {
+		tile = appendBytes(tile, tileLayers, encodeLayer(l))
+	}
+	return tile
+}
+
+func encodeLayer(l Layer) []byte {
+	extent := l.Extent
+	if extent == 0 {
+		extent = DefaultExtent
+	}
+
+	// Keys and values are shared by the features through indices
+	keyIndex := make(map[string]int)
+	valueIndex := make(map[int64]int)
+	var keys []string
+	var values []int64
+	features := make([][]byte, 0, len(l.Features))
+	for _, f := range l.Features {
+		names := make([]string, 0, len(f.Properties))
+		for k := range f.Properties {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		var tags []uint64
+		for _, k := range names {
+			ki, ok := keyIndex[k]
+			if !ok {
+				ki = len(keys)
+				keyIndex[k] = ki
+				keys = append(keys, k)
+			}
+			v := f.Properties[k]
+			vi, ok := valueIndex[v]
+			if !ok {
+				vi = len(values)
+				valueIndex[v] = vi
+				values = append(values, v)
+			}
+			tags = append(tags, uint64(ki), uint64(vi))
+		}
+
+		var feature []byte
+		if len(tags) > 0 {
+			feature = appendPacked(feature, featureTags, tags)
+		}
+		feature = appendVarintField(feature, featureType, geomTypePoint)
+		feature = appendPacked(feature, featureGeometry, []uint64{
+			cmdMoveTo | 1<<3, zigzag(int64(f.X)), zigzag(int64(f.Y)),
+		})
+		features = append(features, feature)
+	}
+
+	var layer []byte
+	layer = appendVarintField(layer, layerVersion, 2)
+	layer = appendBytes(layer, layerName, []byte(l.Name))
+	for _, f := range features {
+		layer = appendBytes(layer, layerFeatures, f)
+	}
+	for _, k := range keys {
+		layer = appendBytes(layer, layerKeys, []byte(k))
+	}
+	for _, v := range values {
+		layer = appendBytes(layer, layerValues, appendVarintField(nil, valueInt, uint64(v)))
+	}
+	layer = appendVarintField(layer, layerExtent, uint64(extent))
+	return layer
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// appendVarintField appends a field of wire type 0
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendVarint(b, uint64(field)<<3)
+	return appendVarint(b, v)
+}
+
+// appendBytes appends a length-delimited field, wire type 2
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendPacked(b []byte, field int, values []uint64) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = appendVarint(packed, v)
+	}
+	return appendBytes(b, field, packed)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
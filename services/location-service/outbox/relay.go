@@ -0,0 +1,94 @@
+// Package outbox publishes the locations queued in the outbox table to
+// Kafka. Locations are queued in the transaction that stores them, so none
+// is lost when Kafka is down or the service stops before publishing; a
+// location may be published more than once, e.g. when marking it sent
+// fails after Kafka accepted it.
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// Config controls the relay
+type Config struct {
+	// Entries published per Kafka write
+	BatchSize int
+	// Delay before the first retry of a failed entry, doubled on each
+	// further failure up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// How long sent entries are kept
+	SentRetention time.Duration
+}
+
+// ConfigFrom converts the environment configuration
+func ConfigFrom(c config.OutboxConfig) Config {
+	return Config{
+		BatchSize:     c.BatchSize,
+		MinBackoff:    time.Duration(c.MinBackoffSeconds) * time.Second,
+		MaxBackoff:    time.Duration(c.MaxBackoffSeconds) * time.Second,
+		SentRetention: time.Duration(c.SentRetentionHours) * time.Hour,
+	}
+}
+
+// Relay moves outbox entries to Kafka
+type Relay struct {
+	cfg     Config
+	publish func(ctx context.Context, locations []*models.Location) error
+}
+
+func NewRelay(cfg Config, publish func(ctx context.Context, locations []*models.Location) error) *Relay {
+	return &Relay{cfg: cfg, publish: publish}
+}
+
+// Run relays entries every interval until ctx is done. A full batch is
+// followed by the next one right away, so a backlog drains at Kafka's pace.
+// Every replica may run a relay; each entry is claimed by one of them.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastPurge := time.Time{}
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil {
+			log.Printf("outbox relay failed: %v", err)
+		}
+		if time.Since(lastPurge) > time.Hour {
+			if _, err := models.PurgeSentOutbox(ctx, time.Now().Add(-r.cfg.SentRetention)); err != nil {
+				log.Printf("failed to purge sent outbox entries: %v", err)
+			}
+			lastPurge = time.Now()
+		}
+		if err == nil && n == r.cfg.BatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes one batch of due entries and returns their number
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	return models.RelayOutbox(ctx, r.cfg.BatchSize, r.backoff, r.publish)
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of failed ones
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.cfg.MinBackoff
+	for i := 1; i < attempts && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.cfg.MaxBackoff {
+		d = r.cfg.MaxBackoff
+	}
+	return d
+}
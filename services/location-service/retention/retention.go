@@ -0,0 +1,165 @@
+// Package retention maintains the monthly partitions of the locations
+// table and removes points past the retention period of their tenant.
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// DefaultDeleteBatch is the number of points deleted per statement
+const DefaultDeleteBatch = 10000
+
+// lockScope serializes maintenance across replicas
+const lockScope = "location-retention"
+
+// Config controls a maintenance run
+type Config struct {
+	// Retention of tenants without settings, in days; 0 keeps points forever
+	DefaultDays int
+	// Months after the current one to create partitions for
+	PremakeMonths int
+	DeleteBatch   int
+}
+
+// Report summarizes a maintenance run
+type Report struct {
+	Created []string
+	Dropped []string
+	// Points deleted from partitions that are kept
+	Deleted int64
+}
+
+// Run performs maintenance every interval until ctx is done, starting
+// immediately
+func Run(ctx context.Context, cfg Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Another replica holding the lock runs the maintenance instead
+		_, err := models.TryWithLock(ctx, lockScope, func() error {
+			report, err := Maintain(ctx, cfg, time.Now())
+			if len(report.Created) > 0 || len(report.Dropped) > 0 || report.Deleted > 0 {
+				log.Printf("location retention: created partitions %v, dropped partitions %v, deleted %d expired points",
+					report.Created, report.Dropped, report.Deleted)
+			}
+			return err
+		})
+		if err != nil {
+			log.Printf("location retention failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Maintain moves points out of the default partition into monthly
+// partitions, creates the partitions of the coming months, drops
+// partitions whose month is past the retention of every tenant, and deletes
+// the expired points left in the remaining partitions. A partition is only
+// dropped when no tenant may keep its points, so a tenant keeping points
+// forever, or the default doing so, keeps every partition.
+func Maintain(ctx context.Context, cfg Config, now time.Time) (Report, error) {
+	var report Report
+	if cfg.DeleteBatch <= 0 {
+		cfg.DeleteBatch = DefaultDeleteBatch
+	}
+
+	months, err := models.DefaultPartitionMonths(ctx)
+	if err != nil {
+		return report, err
+	}
+	current := models.MonthStart(now)
+	for i := 0; i <= cfg.PremakeMonths; i++ {
+		months = append(months, current.AddDate(0, i, 0))
+	}
+	for _, month := range months {
+		p, created, err := models.CreateLocationPartition(ctx, month)
+		if err != nil {
+			return report, err
+		}
+		if created {
+			report.Created = append(report.Created, p.Name)
+		}
+	}
+
+	settings, err := models.ListRetentionSettings(ctx)
+	if err != nil {
+		return report, err
+	}
+	defaults := models.RetentionSettings{RetentionDays: cfg.DefaultDays}
+
+	// Partitions are shared by all tenants, so they expire with the longest
+	// retention
+	longest := defaults.RetentionDays
+	for _, s := range settings {
+		if longest == 0 || s.RetentionDays == 0 {
+			longest = 0
+			break
+		}
+		longest = max(longest, s.RetentionDays)
+	}
+	if longest > 0 {
+		cutoff := now.AddDate(0, 0, -longest)
+		partitions, err := models.ListLocationPartitions(ctx)
+		if err != nil {
+			return report, err
+		}
+		for _, p := range partitions {
+			if p.To.After(cutoff) {
+				break
+			}
+			if err := models.DropLocationPartition(ctx, p); err != nil {
+				return report, err
+			}
+			report.Dropped = append(report.Dropped, p.Name)
+		}
+	}
+
+	// Tenants with the longest retention still have expired points in the
+	// partition whose month straddles their cutoff; after the drops the
+	// delete only scans what is left before it
+	tenantIDs := make([]string, 0, len(settings))
+	for _, s := range settings {
+		tenantIDs = append(tenantIDs, s.TenantID)
+		if s.RetentionDays > 0 {
+			tenantID, cutoff := s.TenantID, s.Cutoff(now)
+			n, err := deleteAll(func() (int64, error) {
+				return models.DeleteExpiredLocations(ctx, tenantID, cutoff, cfg.DeleteBatch)
+			}, cfg.DeleteBatch)
+			report.Deleted += n
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+	if defaults.RetentionDays > 0 {
+		cutoff := defaults.Cutoff(now)
+		n, err := deleteAll(func() (int64, error) {
+			return models.DeleteExpiredLocationsExcept(ctx, tenantIDs, cutoff, cfg.DeleteBatch)
+		}, cfg.DeleteBatch)
+		report.Deleted += n
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// deleteAll repeats a batched delete until a batch comes back short
+func deleteAll(batch func() (int64, error), size int) (int64, error) {
+	var total int64
+	for {
+		n, err := batch()
+		total += n
+		if err != nil || n < int64(size) {
+			return total, err
+		}
+	}
+}
@@ -2,10 +2,11 @@ package streaming
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 	"log"
 	"time"
 
+	"github.com/himanshum9/go-mithril/services/location-service/models"
 	"github.com/segmentio/kafka-go"
 )
 
@@ -24,10 +25,7 @@ func NewStreamer(brokerAddress string, topic string) *Streamer {
 }
 
 func (s *Streamer) StreamLocationData(tenantID string, latitude float64, longitude float64, timestamp time.Time) error {
-	message := kafka.Message{
-		Key:   []byte(tenantID),
-		Value: []byte(fmt.Sprintf(`{"latitude": %f, "longitude": %f, "timestamp": "%s"}`, latitude, longitude, timestamp.Format(time.RFC3339))),
-	}
+	message := locationMessage(&models.Location{TenantID: tenantID, Latitude: latitude, Longitude: longitude, Timestamp: timestamp})
 
 	err := s.writer.WriteMessages(context.Background(), message)
 	if err != nil {
@@ -37,6 +35,133 @@ func (s *Streamer) StreamLocationData(tenantID string, latitude float64, longitu
 	return nil
 }
 
+// StreamLocations writes all locations in a single WriteMessages call
+func (s *Streamer) StreamLocations(ctx context.Context, locations []*models.Location) error {
+	messages := make([]kafka.Message, 0, len(locations))
+	for _, l := range locations {
+		messages = append(messages, locationMessage(l))
+	}
+
+	err := s.writer.WriteMessages(ctx, messages...)
+	if err != nil {
+		log.Printf("failed to write %d messages: %v", len(messages), err)
+		return err
+	}
+	return nil
+}
+
+// ClientPointIDHeader carries the client-supplied point ID so consumers can
+// drop redelivered messages
+const ClientPointIDHeader = "client_point_id"
+
+// locationPayload is the JSON value of a location-stream message
+type locationPayload struct {
+	TenantID  string  `json:"tenant_id"`
+	UserID    string  `json:"user_id,omitempty"`
+	SessionID string  `json:"session_id,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timestamp string  `json:"timestamp"`
+	models.Telemetry
+}
+
+// locationMessage keys the message by tenant and user, like the other
+// topics, so the points of a user stay ordered within a partition and the
+// user's tombstone compacts them away
+func locationMessage(l *models.Location) kafka.Message {
+	value, _ := json.Marshal(locationPayload{
+		TenantID:  l.TenantID,
+		UserID:    l.UserID,
+		SessionID: l.SessionID,
+		Latitude:  l.Latitude,
+		Longitude: l.Longitude,
+		Timestamp: l.Timestamp.Format(time.RFC3339),
+		Telemetry: l.Telemetry,
+	})
+	message := kafka.Message{
+		Key:   []byte(l.TenantID + "/" + l.UserID),
+		Value: value,
+	}
+	if l.ClientPointID != "" {
+		message.Headers = append(message.Headers, kafka.Header{Key: ClientPointIDHeader, Value: []byte(l.ClientPointID)})
+	}
+	return message
+}
+
+// StreamGeofenceEvents writes geofence events keyed by tenant and user, so
+// the events of a user stay ordered within a partition
+func (s *Streamer) StreamGeofenceEvents(ctx context.Context, events []models.GeofenceEvent) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, e := range events {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{
+			Key:     []byte(e.TenantID + "/" + e.UserID),
+			Value:   value,
+			Headers: []kafka.Header{{Key: EventTypeHeader, Value: []byte(e.Type)}},
+		})
+	}
+
+	err := s.writer.WriteMessages(ctx, messages...)
+	if err != nil {
+		log.Printf("failed to write %d geofence events: %v", len(messages), err)
+		return err
+	}
+	return nil
+}
+
+// StreamAnomalyAlerts writes anomaly alerts keyed by tenant and user
+func (s *Streamer) StreamAnomalyAlerts(ctx context.Context, alerts []models.AnomalyAlert) error {
+	messages := make([]kafka.Message, 0, len(alerts))
+	for _, a := range alerts {
+		value, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{
+			Key:     []byte(a.TenantID + "/" + a.UserID),
+			Value:   value,
+			Headers: []kafka.Header{{Key: EventTypeHeader, Value: []byte(models.AnomalyAlertType)}},
+		})
+	}
+
+	err := s.writer.WriteMessages(ctx, messages...)
+	if err != nil {
+		log.Printf("failed to write %d anomaly alerts: %v", len(messages), err)
+		return err
+	}
+	return nil
+}
+
+// EventTypeHeader carries the event type, e.g. geofence.enter, so consumers
+// can filter without decoding the value
+const EventTypeHeader = "event_type"
+
+// UserErasedEvent is the event type of tombstones
+const UserErasedEvent = "user.erased"
+
+// PublishTombstone writes a message without value keyed by tenant and user,
+// telling consumers to delete what they hold about the user. Compaction
+// drops the earlier messages of the user, which are keyed the same way.
+func (s *Streamer) PublishTombstone(ctx context.Context, tenantID, userID string) error {
+	err := s.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(tenantID + "/" + userID),
+		Headers: []kafka.Header{{Key: EventTypeHeader, Value: []byte(UserErasedEvent)}},
+	})
+	if err != nil {
+		log.Printf("failed to write tombstone for tenant %s user %s: %v", tenantID, userID, err)
+		return err
+	}
+	return nil
+}
+
+// Topic returns the topic messages are written to
+func (s *Streamer) Topic() string {
+	return s.writer.Topic
+}
+
 func (s *Streamer) Close() error {
 	return s.writer.Close()
 }
@@ -0,0 +1,160 @@
+package trips
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// lockScope serializes incremental runs and backfill chunks of a user
+const lockScope = "trips"
+
+// staleBackfill is how long a backfill may go without progress before
+// incremental runs take over again, e.g. after the backfill was killed
+const staleBackfill = 10 * time.Minute
+
+// backfillBatchSize is the number of points processed per backfill
+// transaction
+const backfillBatchSize = 1000
+
+// Processor segments stored locations and persists the resulting trips and
+// stops
+type Processor struct {
+	cfg Config
+}
+
+func NewProcessor(cfg Config) *Processor {
+	return &Processor{cfg: cfg}
+}
+
+// Process feeds newly stored points of a tenant user to their segmenter.
+// Points older than the last processed one are ignored. While a backfill
+// of the user runs, nothing is done: the backfill reads the points itself.
+func (p *Processor) Process(ctx context.Context, tenantID, userID string, locations []*models.Location) error {
+	if len(locations) == 0 {
+		return nil
+	}
+	points := make([]Point, len(locations))
+	for i, l := range locations {
+		points[i] = pointOf(l)
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+
+	return models.WithUserLock(ctx, lockScope, tenantID, userID, func(tx *sql.Tx) error {
+		stored, state, err := loadState(ctx, tx, tenantID, userID)
+		if err != nil {
+			return err
+		}
+		if stored.BackfillAt != nil && time.Since(*stored.BackfillAt) < staleBackfill {
+			return nil
+		}
+		var out Segments
+		for _, pt := range points {
+			state.Add(p.cfg, pt, &out)
+		}
+		return saveState(ctx, tx, tenantID, userID, state, &out, nil)
+	})
+}
+
+// Backfill rebuilds the trips and stops of a tenant user from their whole
+// location history. It works in batches so incremental runs of the user
+// only wait for one batch at a time. The open trip or stop at the end of
+// the history is kept for incremental runs to continue. It returns the
+// number of trips and stops stored.
+func (p *Processor) Backfill(ctx context.Context, tenantID, userID string) (int, int, error) {
+	var trips, stops int
+	started := time.Now()
+	err := models.WithUserLock(ctx, lockScope, tenantID, userID, func(tx *sql.Tx) error {
+		if err := models.DeleteTripsAndStops(ctx, tx, tenantID, userID); err != nil {
+			return err
+		}
+		return saveState(ctx, tx, tenantID, userID, &State{}, &Segments{}, &started)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	q := models.LocationQuery{
+		Params:   pagination.Params{Limit: backfillBatchSize, Order: pagination.OrderAsc},
+		TenantID: tenantID,
+		UserID:   userID,
+	}
+	for {
+		done := false
+		err := models.WithUserLock(ctx, lockScope, tenantID, userID, func(tx *sql.Tx) error {
+			_, state, err := loadState(ctx, tx, tenantID, userID)
+			if err != nil {
+				return err
+			}
+			page, err := models.QueryLocations(ctx, q)
+			if err != nil {
+				return err
+			}
+			var out Segments
+			for i := range page.Items {
+				state.Add(p.cfg, pointOf(&page.Items[i]), &out)
+			}
+
+			// The last batch is read under the lock, so points stored after it
+			// are left to incremental runs
+			now := time.Now()
+			backfillAt := &now
+			if page.NextCursor == "" {
+				done, backfillAt = true, nil
+			} else if q.Cursor, err = pagination.DecodeCursor(page.NextCursor); err != nil {
+				return err
+			}
+			if err := saveState(ctx, tx, tenantID, userID, state, &out, backfillAt); err != nil {
+				return err
+			}
+			trips += len(out.Trips)
+			stops += len(out.Stops)
+			return nil
+		})
+		if err != nil || done {
+			return trips, stops, err
+		}
+	}
+}
+
+func loadState(ctx context.Context, tx *sql.Tx, tenantID, userID string) (models.TripSegmenterState, *State, error) {
+	stored, err := models.GetTripSegmenterState(ctx, tx, tenantID, userID)
+	if err != nil {
+		return stored, nil, err
+	}
+	state := &State{}
+	if len(stored.State) > 0 {
+		if err := json.Unmarshal(stored.State, state); err != nil {
+			return stored, nil, err
+		}
+	}
+	return stored, state, nil
+}
+
+// saveState stores the completed segments and the state of the user
+func saveState(ctx context.Context, tx *sql.Tx, tenantID, userID string, state *State, out *Segments, backfillAt *time.Time) error {
+	for i := range out.Trips {
+		t := &out.Trips[i]
+		t.TenantID, t.UserID = tenantID, userID
+		if err := models.SaveTrip(ctx, tx, t); err != nil {
+			return err
+		}
+	}
+	for i := range out.Stops {
+		s := &out.Stops[i]
+		s.TenantID, s.UserID = tenantID, userID
+		if err := models.SaveStop(ctx, tx, s); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return models.SaveTripSegmenterState(ctx, tx, tenantID, userID, models.TripSegmenterState{State: b, BackfillAt: backfillAt})
+}
@@ -0,0 +1,219 @@
+// Package trips segments the location stream of each user into trips and
+// stops.
+package trips
+
+import (
+	"math"
+	"time"
+
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/services/location-service/geometry"
+	"github.com/himanshum9/go-mithril/services/location-service/models"
+)
+
+// Config holds the segmentation thresholds. A stop is a stay within
+// StopRadiusMeters for at least StopMinDuration, made of points reporting
+// at most StopMaxSpeed. A gap longer than MaxGap ends the current trip or
+// stop. Trips shorter than MinTripDistance are dropped as jitter.
+type Config struct {
+	StopRadiusMeters float64
+	StopMinDuration  time.Duration
+	StopMaxSpeed     float64
+	MaxGap           time.Duration
+	MinTripDistance  float64
+}
+
+// ConfigFrom converts the environment configuration
+func ConfigFrom(c config.TripConfig) Config {
+	return Config{
+		StopRadiusMeters: c.StopRadiusMeters,
+		StopMinDuration:  time.Duration(c.StopMinSeconds) * time.Second,
+		StopMaxSpeed:     c.StopMaxSpeed,
+		MaxGap:           time.Duration(c.MaxGapSeconds) * time.Second,
+		MinTripDistance:  c.MinTripDistanceMeters,
+	}
+}
+
+// Point is the part of a location the segmenter needs
+type Point struct {
+	Lat   float64   `json:"lat"`
+	Lon   float64   `json:"lon"`
+	Time  time.Time `json:"t"`
+	Speed *float64  `json:"speed,omitempty"`
+}
+
+func pointOf(l *models.Location) Point {
+	return Point{Lat: l.Latitude, Lon: l.Longitude, Time: l.Timestamp, Speed: l.Speed}
+}
+
+func (p Point) geo() geometry.Point {
+	return geometry.Point{Lat: p.Lat, Lon: p.Lon}
+}
+
+// State is the progress of one user's segmentation, stored between runs.
+// Candidate holds the points of a possible stop until it lasts long enough
+// to be confirmed; Stop and Trip are the open segments.
+type State struct {
+	Last      *Point    `json:"last,omitempty"`
+	Candidate []Point   `json:"candidate,omitempty"`
+	Stop      *openStop `json:"stop,omitempty"`
+	Trip      *openTrip `json:"trip,omitempty"`
+}
+
+type openStop struct {
+	First  Point   `json:"first"`
+	Last   Point   `json:"last"`
+	SumLat float64 `json:"sum_lat"`
+	SumLon float64 `json:"sum_lon"`
+	Count  int     `json:"count"`
+}
+
+func (s *openStop) center() geometry.Point {
+	return geometry.Point{Lat: s.SumLat / float64(s.Count), Lon: s.SumLon / float64(s.Count)}
+}
+
+func (s *openStop) add(p Point) {
+	s.Last = p
+	s.SumLat += p.Lat
+	s.SumLon += p.Lon
+	s.Count++
+}
+
+type openTrip struct {
+	Points   []Point `json:"points"`
+	Distance float64 `json:"distance"`
+	MaxSpeed float64 `json:"max_speed"`
+}
+
+// Segments are the trips and stops completed by a call to Add or Flush
+type Segments struct {
+	Trips []models.Trip
+	Stops []models.Stop
+}
+
+// Add feeds the next point of the user. Points not newer than the last one
+// are ignored.
+func (s *State) Add(cfg Config, p Point, out *Segments) {
+	if s.Last != nil {
+		if !p.Time.After(s.Last.Time) {
+			return
+		}
+		if p.Time.Sub(s.Last.Time) > cfg.MaxGap {
+			s.Flush(cfg, out)
+		}
+	}
+	s.Last = &p
+	slow := p.Speed == nil || *p.Speed <= cfg.StopMaxSpeed
+
+	if s.Stop != nil {
+		if slow && geometry.Haversine(s.Stop.center(), p.geo()) <= cfg.StopRadiusMeters {
+			s.Stop.add(p)
+			return
+		}
+		// Leaving the stop starts a trip at its last point
+		last := s.Stop.Last
+		s.closeStop(out)
+		s.appendTrip(last)
+	}
+
+	if len(s.Candidate) > 0 && (!slow || geometry.Haversine(s.Candidate[0].geo(), p.geo()) > cfg.StopRadiusMeters) {
+		// Moved on too early: the candidate points were part of a trip
+		s.appendTrip(s.Candidate...)
+		s.Candidate = nil
+	}
+	if !slow {
+		s.appendTrip(p)
+		return
+	}
+	s.Candidate = append(s.Candidate, p)
+	if p.Time.Sub(s.Candidate[0].Time) < cfg.StopMinDuration {
+		return
+	}
+
+	// Confirmed stop: the open trip ends where the stop begins
+	if s.Trip != nil {
+		s.appendTrip(s.Candidate[0])
+		s.closeTrip(cfg, out)
+	}
+	s.Stop = &openStop{First: s.Candidate[0], Last: s.Candidate[0]}
+	s.Stop.SumLat, s.Stop.SumLon, s.Stop.Count = s.Candidate[0].Lat, s.Candidate[0].Lon, 1
+	for _, c := range s.Candidate[1:] {
+		s.Stop.add(c)
+	}
+	s.Candidate = nil
+}
+
+// Flush completes the open trip or stop, as after a gap in the data
+func (s *State) Flush(cfg Config, out *Segments) {
+	if s.Stop != nil {
+		s.closeStop(out)
+	}
+	if len(s.Candidate) > 0 {
+		s.appendTrip(s.Candidate...)
+		s.Candidate = nil
+	}
+	if s.Trip != nil {
+		s.closeTrip(cfg, out)
+	}
+}
+
+func (s *State) appendTrip(points ...Point) {
+	if s.Trip == nil {
+		s.Trip = &openTrip{}
+	}
+	t := s.Trip
+	for _, p := range points {
+		if p.Speed != nil {
+			t.MaxSpeed = math.Max(t.MaxSpeed, *p.Speed)
+		}
+		if n := len(t.Points); n > 0 {
+			prev := t.Points[n-1]
+			if !p.Time.After(prev.Time) {
+				continue
+			}
+			d := geometry.Haversine(prev.geo(), p.geo())
+			t.Distance += d
+			if p.Speed == nil {
+				t.MaxSpeed = math.Max(t.MaxSpeed, d/p.Time.Sub(prev.Time).Seconds())
+			}
+		}
+		t.Points = append(t.Points, p)
+	}
+}
+
+func (s *State) closeTrip(cfg Config, out *Segments) {
+	t := s.Trip
+	s.Trip = nil
+	if len(t.Points) < 2 || t.Distance < cfg.MinTripDistance {
+		return
+	}
+	first, last := t.Points[0], t.Points[len(t.Points)-1]
+	line := make([]geometry.Point, len(t.Points))
+	for i, p := range t.Points {
+		line[i] = p.geo()
+	}
+	out.Trips = append(out.Trips, models.Trip{
+		StartedAt:      first.Time,
+		EndedAt:        last.Time,
+		Start:          models.Coordinate{Latitude: first.Lat, Longitude: first.Lon},
+		End:            models.Coordinate{Latitude: last.Lat, Longitude: last.Lon},
+		DurationSecs:   int64(last.Time.Sub(first.Time) / time.Second),
+		DistanceMeters: t.Distance,
+		MaxSpeed:       t.MaxSpeed,
+		PointCount:     len(t.Points),
+		Polyline:       geometry.EncodePolyline(line),
+	})
+}
+
+func (s *State) closeStop(out *Segments) {
+	st := s.Stop
+	s.Stop = nil
+	c := st.center()
+	out.Stops = append(out.Stops, models.Stop{
+		StartedAt:    st.First.Time,
+		EndedAt:      st.Last.Time,
+		Location:     models.Coordinate{Latitude: c.Lat, Longitude: c.Lon},
+		DurationSecs: int64(st.Last.Time.Sub(st.First.Time) / time.Second),
+		PointCount:   st.Count,
+	})
+}
@@ -9,10 +9,17 @@ import (
 )
 
 type LocationData struct {
-	TenantID  string  `json:"tenant_id"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Timestamp string  `json:"timestamp"`
+	TenantID     string            `json:"tenant_id"`
+	Latitude     float64           `json:"latitude"`
+	Longitude    float64           `json:"longitude"`
+	Timestamp    string            `json:"timestamp"`
+	Accuracy     *float64          `json:"accuracy,omitempty"`
+	Altitude     *float64          `json:"altitude,omitempty"`
+	Speed        *float64          `json:"speed,omitempty"`
+	Heading      *float64          `json:"heading,omitempty"`
+	BatteryLevel *float64          `json:"battery_level,omitempty"`
+	Provider     string            `json:"provider,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
 }
 
 type ThirdPartyClient struct {
@@ -1,16 +1,37 @@
 package handlers
 
 import (
+    "crypto/subtle"
     "encoding/json"
     "net/http"
+    "strings"
+    "time"
     "github.com/gorilla/mux"
+    "github.com/himanshum9/go-mithril/pkg/apierror"
+    "github.com/himanshum9/go-mithril/pkg/featureflags"
+    "github.com/himanshum9/go-mithril/pkg/privacy"
+    "github.com/himanshum9/go-mithril/pkg/validation"
     "log"
 )
 
+var (
+    featureFlags    *featureflags.Client
+    privacyPolicies *privacy.Client
+)
+
 type StreamRequest struct {
-    TenantID    string  `json:"tenant_id"`
-    Latitude    float64 `json:"latitude"`
-    Longitude   float64 `json:"longitude"`
+    TenantID     string            `json:"tenant_id"`
+    UserID       string            `json:"user_id,omitempty"`
+    Latitude     float64           `json:"latitude"`
+    Longitude    float64           `json:"longitude"`
+    Timestamp    string            `json:"timestamp,omitempty"`
+    Accuracy     *float64          `json:"accuracy,omitempty"`
+    Altitude     *float64          `json:"altitude,omitempty"`
+    Speed        *float64          `json:"speed,omitempty"`
+    Heading      *float64          `json:"heading,omitempty"`
+    BatteryLevel *float64          `json:"battery_level,omitempty"`
+    Provider     string            `json:"provider,omitempty"`
+    Metadata     map[string]string `json:"metadata,omitempty"`
 }
 
 func StreamLocationData(w http.ResponseWriter, r *http.Request) {
@@ -18,10 +39,44 @@ func StreamLocationData(w http.ResponseWriter, r *http.Request) {
 
     err := json.NewDecoder(r.Body).Decode(&request)
     if err != nil {
-        http.Error(w, "Invalid request payload", http.StatusBadRequest)
+        apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, "Invalid request payload")
+        return
+    }
+
+    v := validation.New()
+    v.Required("tenant_id", request.TenantID != "")
+    v.Latitude("latitude", &request.Latitude)
+    v.Longitude("longitude", &request.Longitude)
+    if err := v.Err(); err != nil {
+        apierror.WriteValidation(w, v.Errors())
+        return
+    }
+
+    if featureFlags != nil && !featureFlags.Enabled(r.Context(), request.TenantID, featureflags.ThirdPartyStreaming) {
+        http.Error(w, "Third-party streaming is not enabled for this tenant", http.StatusForbidden)
         return
     }
 
+    // The third party only receives what the privacy policy allows
+    if privacyPolicies != nil {
+        policy, err := privacyPolicies.Policy(r.Context(), request.TenantID, request.UserID)
+        if err != nil {
+            log.Printf("privacy policies: %v", err)
+            http.Error(w, "Privacy policy unavailable", http.StatusServiceUnavailable)
+            return
+        }
+        t := time.Now()
+        if ts, err := time.Parse(time.RFC3339, request.Timestamp); err == nil {
+            t = ts
+        }
+        if policy.Drops(t) {
+            w.WriteHeader(http.StatusAccepted)
+            json.NewEncoder(w).Encode(map[string]string{"status": "discarded"})
+            return
+        }
+        request.Latitude, request.Longitude = policy.Apply(request.Latitude, request.Longitude, t)
+    }
+
     // Here you would typically process the location data and stream it to the third-party application.
     // For now, we'll just log the received data.
     log.Printf("Received location data: TenantID=%s, Latitude=%f, Longitude=%f", request.TenantID, request.Latitude, request.Longitude)
@@ -31,6 +86,23 @@ func StreamLocationData(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
-func RegisterRoutes(r *mux.Router) {
-    r.HandleFunc("/stream", StreamLocationData).Methods("POST")
+// RegisterRoutes serves /stream to services sending serviceToken as their
+// bearer token, as the tenant of a message is taken from its body
+func RegisterRoutes(r *mux.Router, flags *featureflags.Client, policies *privacy.Client, serviceToken string) {
+    featureFlags = flags
+    privacyPolicies = policies
+    r.Handle("/stream", serviceAuth(serviceToken, StreamLocationData)).Methods("POST")
+}
+
+// serviceAuth admits callers sending token as their bearer token. Without a
+// token nobody is admitted.
+func serviceAuth(token string, next http.HandlerFunc) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+        if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+            return
+        }
+        next(w, r)
+    })
 }
\ No newline at end of file
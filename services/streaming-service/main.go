@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/pkg/featureflags"
+	"github.com/himanshum9/go-mithril/pkg/privacy"
+	"github.com/himanshum9/go-mithril/services/auth-service/middleware"
 )
 
 var (
@@ -16,13 +22,24 @@ var (
 	upgrader  = websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
 	}
+	featureFlags    *featureflags.Client
+	privacyPolicies *privacy.Client
 )
 
 func main() {
+	cfg := config.Load()
+	featureFlags = featureflags.NewClient(cfg.FeatureFlags.TenantServiceURL, cfg.GetFeatureFlagRefreshInterval(), map[string]bool{
+		featureflags.LiveWebSocket:       true,
+		featureflags.ThirdPartyStreaming: true,
+	})
+	defer featureFlags.Close()
+	privacyPolicies = privacy.NewClient(cfg.Privacy.LocationServiceURL, cfg.Privacy.ServiceToken, cfg.GetPrivacyRefreshInterval())
+	defer privacyPolicies.Close()
+
 	router := mux.NewRouter()
 
 	// WebSocket endpoint
-	router.HandleFunc("/ws", wsHandler)
+	router.Handle("/ws", middleware.CognitoAuthMiddleware(http.HandlerFunc(wsHandler)))
 
 	// HTTP endpoint to receive location data and broadcast to WebSocket clients
 	router.HandleFunc("/stream", streamHandler).Methods("POST")
@@ -33,7 +50,17 @@ func main() {
 	}
 }
 
+// wsHandler serves the live view to users of the tenant of their token
 func wsHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := middleware.GetClaimsFromContext(r.Context())["tenant_id"].(string)
+	if tenantID == "" {
+		http.Error(w, "Forbidden: no tenant", http.StatusForbidden)
+		return
+	}
+	if !featureFlags.Enabled(r.Context(), tenantID, featureflags.LiveWebSocket) {
+		http.Error(w, "Live view is not enabled for this tenant", http.StatusForbidden)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -62,11 +89,52 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	deliver, err := applyPrivacy(r.Context(), msg)
+	if err != nil {
+		log.Printf("privacy policies: %v", err)
+		http.Error(w, "Privacy policy unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if !deliver {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Location discarded outside working hours"))
+		return
+	}
 	broadcastToWebSocketClients(msg)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Streaming location data..."))
 }
 
+// applyPrivacy rounds or blurs the position of a location message under
+// the policy of its tenant user, and reports whether the message may be
+// delivered at all. Messages without a tenant_id have no policy.
+func applyPrivacy(ctx context.Context, msg map[string]interface{}) (bool, error) {
+	tenantID, _ := msg["tenant_id"].(string)
+	if tenantID == "" {
+		return true, nil
+	}
+	userID, _ := msg["user_id"].(string)
+	policy, err := privacyPolicies.Policy(ctx, tenantID, userID)
+	if err != nil {
+		return false, err
+	}
+	t := time.Now()
+	if s, ok := msg["timestamp"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339, s); err == nil {
+			t = parsed
+		}
+	}
+	if policy.Drops(t) {
+		return false, nil
+	}
+	lat, latOK := msg["latitude"].(float64)
+	lon, lonOK := msg["longitude"].(float64)
+	if latOK && lonOK {
+		msg["latitude"], msg["longitude"] = policy.Apply(lat, lon, t)
+	}
+	return true, nil
+}
+
 func broadcastToWebSocketClients(msg interface{}) {
 	clientsMu.Lock()
 	defer clientsMu.Unlock()
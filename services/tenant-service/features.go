@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/himanshum9/go-mithril/pkg/featureflags"
+	"github.com/himanshum9/go-mithril/services/tenant-service/models"
+)
+
+// adminAuth admits callers sending token as their bearer token, for the
+// routes that change flags. Without a token nobody is admitted.
+func adminAuth(token string) func(http.HandlerFunc) http.Handler {
+	return func(next http.HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		})
+	}
+}
+
+func listFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := models.ListFeatureFlags(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(flags)
+}
+
+func getFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	f, err := models.GetFeatureFlag(r.Context(), mux.Vars(r)["key"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "feature flag not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(f)
+}
+
+func putFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	var f models.FeatureFlag
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.Key = mux.Vars(r)["key"]
+	if f.RolloutPercentage < 0 || f.RolloutPercentage > 100 {
+		http.Error(w, "rollout_percentage must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	if err := models.SaveFeatureFlag(r.Context(), &f); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(f)
+}
+
+func putFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	var o models.FeatureFlagOverride
+	if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	vars := mux.Vars(r)
+	o.FlagKey = vars["key"]
+	o.TenantID = vars["tenant_id"]
+	if err := models.SaveFeatureFlagOverride(r.Context(), &o); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(o)
+}
+
+func deleteFeatureFlagOverride(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := models.DeleteFeatureFlagOverride(r.Context(), vars["key"], vars["tenant_id"]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getTenantFeatures serves the evaluated flags consumed by featureflags.Client
+func getTenantFeatures(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["id"]
+	// Clients cache what they get, so unknown tenants get nothing to cache
+	if _, err := models.GetTenant(r.Context(), tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "tenant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	features, err := models.EvaluateFeatureFlags(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(featureflags.TenantFeatures{TenantID: tenantID, Features: features})
+}
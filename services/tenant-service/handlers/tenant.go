@@ -5,6 +5,8 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
 	"github.com/himanshum9/go-mithril/services/tenant-service/models"
 )
 
@@ -24,7 +26,11 @@ func CreateTenant(c *gin.Context) {
 
 	var tenant models.Tenant
 	if err := c.ShouldBindJSON(&tenant); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, apierror.New(apierror.CodeInvalidRequest, err.Error()))
+		return
+	}
+	if err := tenant.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, apierror.New(apierror.CodeValidationFailed, "Request validation failed", err.(validation.Errors)...))
 		return
 	}
 
@@ -55,7 +61,7 @@ func GetTenant(c *gin.Context) {
 	c.JSON(http.StatusOK, tenant)
 }
 
-// ListTenants retrieves all tenants
+// ListTenants retrieves a page of tenants
 func ListTenants(c *gin.Context) {
 	claims, exists := c.Get("claims")
 	if !exists {
@@ -69,7 +75,23 @@ func ListTenants(c *gin.Context) {
 		return
 	}
 
-	// Retrieve all tenants from DB
-	var tenants []models.Tenant // Assume we fetched the tenants from the database
+	opts, err := models.ListTenantsOptionsFromQuery(c.Request.URL.Query())
+	if err != nil {
+		if fields := models.ListTenantsError(err); fields != nil {
+			c.JSON(http.StatusBadRequest, apierror.New(apierror.CodeValidationFailed, "Request validation failed", fields...))
+			return
+		}
+		c.JSON(http.StatusBadRequest, apierror.New(apierror.CodeInvalidRequest, err.Error()))
+		return
+	}
+	tenants, err := models.ListTenants(c.Request.Context(), opts)
+	if fields := models.ListTenantsError(err); fields != nil {
+		c.JSON(http.StatusBadRequest, apierror.New(apierror.CodeValidationFailed, "Request validation failed", fields...))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, tenants)
 }
@@ -8,6 +8,9 @@ import (
 	"os"
 
 	"github.com/gorilla/mux"
+	config "github.com/himanshum9/go-mithril/configs"
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/validation"
 	"github.com/himanshum9/go-mithril/services/tenant-service/models"
 )
 
@@ -20,10 +23,19 @@ func main() {
 		log.Fatalf("DB connection failed: %v", err)
 	}
 
+	cfg := config.Load()
+	admin := adminAuth(cfg.FeatureFlags.AdminToken)
+
 	router := mux.NewRouter()
 	router.HandleFunc("/tenants", createTenant).Methods("POST")
 	router.HandleFunc("/tenants/{id}", getTenant).Methods("GET")
 	router.HandleFunc("/tenants", listTenants).Methods("GET")
+	router.HandleFunc("/tenants/{id}/features", getTenantFeatures).Methods("GET")
+	router.HandleFunc("/features", listFeatureFlags).Methods("GET")
+	router.HandleFunc("/features/{key}", getFeatureFlag).Methods("GET")
+	router.Handle("/features/{key}", admin(putFeatureFlag)).Methods("PUT")
+	router.Handle("/features/{key}/overrides/{tenant_id}", admin(putFeatureFlagOverride)).Methods("PUT")
+	router.Handle("/features/{key}/overrides/{tenant_id}", admin(deleteFeatureFlagOverride)).Methods("DELETE")
 
 	log.Println("Starting tenant service on :8080")
 	if err := http.ListenAndServe(":8080", router); err != nil {
@@ -35,7 +47,11 @@ func main() {
 func createTenant(w http.ResponseWriter, r *http.Request) {
 	var t models.Tenant
 	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	if err := t.Validate(); err != nil {
+		apierror.WriteValidation(w, err.(validation.Errors))
 		return
 	}
 	if err := models.SaveTenant(context.Background(), &t); err != nil {
@@ -58,10 +74,28 @@ func getTenant(w http.ResponseWriter, r *http.Request) {
 }
 
 func listTenants(w http.ResponseWriter, r *http.Request) {
-	tenants, err := models.ListTenants(context.Background())
+	opts, err := models.ListTenantsOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		writeListTenantsError(w, err)
+		return
+	}
+	tenants, err := models.ListTenants(r.Context(), opts)
+	if fields := models.ListTenantsError(err); fields != nil {
+		apierror.WriteValidation(w, fields)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	json.NewEncoder(w).Encode(tenants)
 }
+
+// writeListTenantsError answers list parameters that cannot be read
+func writeListTenantsError(w http.ResponseWriter, err error) {
+	if fields := models.ListTenantsError(err); fields != nil {
+		apierror.WriteValidation(w, fields)
+		return
+	}
+	apierror.Write(w, http.StatusBadRequest, apierror.CodeInvalidRequest, err.Error())
+}
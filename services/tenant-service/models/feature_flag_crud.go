@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+)
+
+func SaveFeatureFlag(ctx context.Context, f *FeatureFlag) error {
+	return DB.QueryRowContext(ctx, `INSERT INTO feature_flags (key, description, enabled, rollout_percentage) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET description = EXCLUDED.description, enabled = EXCLUDED.enabled, rollout_percentage = EXCLUDED.rollout_percentage, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`, f.Key, f.Description, f.Enabled, f.RolloutPercentage).Scan(&f.UpdatedAt)
+}
+
+func GetFeatureFlag(ctx context.Context, key string) (*FeatureFlag, error) {
+	row := DB.QueryRowContext(ctx, `SELECT key, description, enabled, rollout_percentage, updated_at FROM feature_flags WHERE key = $1`, key)
+	var f FeatureFlag
+	if err := row.Scan(&f.Key, &f.Description, &f.Enabled, &f.RolloutPercentage, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func ListFeatureFlags(ctx context.Context) ([]FeatureFlag, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT key, description, enabled, rollout_percentage, updated_at FROM feature_flags ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	flags := []FeatureFlag{}
+	for rows.Next() {
+		var f FeatureFlag
+		if err := rows.Scan(&f.Key, &f.Description, &f.Enabled, &f.RolloutPercentage, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+func SaveFeatureFlagOverride(ctx context.Context, o *FeatureFlagOverride) error {
+	return DB.QueryRowContext(ctx, `INSERT INTO feature_flag_overrides (flag_key, tenant_id, enabled) VALUES ($1, $2, $3)
+		ON CONFLICT (flag_key, tenant_id) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`, o.FlagKey, o.TenantID, o.Enabled).Scan(&o.UpdatedAt)
+}
+
+func DeleteFeatureFlagOverride(ctx context.Context, flagKey, tenantID string) error {
+	_, err := DB.ExecContext(ctx, `DELETE FROM feature_flag_overrides WHERE flag_key = $1 AND tenant_id = $2`, flagKey, tenantID)
+	return err
+}
+
+// EvaluateFeatureFlags returns the state of every flag for tenantID
+func EvaluateFeatureFlags(ctx context.Context, tenantID string) (map[string]bool, error) {
+	flags, err := ListFeatureFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(flags))
+	for i := range flags {
+		result[flags[i].Key] = flags[i].EnabledFor(tenantID)
+	}
+
+	rows, err := DB.QueryContext(ctx, `SELECT flag_key, enabled FROM feature_flag_overrides WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var enabled bool
+		if err := rows.Scan(&key, &enabled); err != nil {
+			return nil, err
+		}
+		result[key] = enabled
+	}
+	return result, rows.Err()
+}
@@ -1,9 +1,35 @@
 package models
 
+import (
+    "time"
+
+    "github.com/himanshum9/go-mithril/pkg/validation"
+)
+
+const (
+    TenantStatusActive    = "active"
+    TenantStatusSuspended = "suspended"
+)
+
 type Tenant struct {
     TenantID   string `json:"tenant_id"`
     Name       string `json:"name"`
     Description string `json:"description"`
+    Status     string    `json:"status"`
+    CreatedAt  time.Time `json:"created_at"`
+}
+
+// Validate checks the fields required to create a tenant
+func (t *Tenant) Validate() error {
+    v := validation.New()
+    if v.Required("tenant_id", t.TenantID != "") {
+        v.MaxLength("tenant_id", t.TenantID, 255)
+    }
+    if v.Required("name", t.Name != "") {
+        v.MaxLength("name", t.Name, 255)
+    }
+    v.OneOf("status", t.Status, TenantStatusActive, TenantStatusSuspended)
+    return v.Err()
 }
 
 func NewTenant(tenantID, name, description string) *Tenant {
@@ -11,5 +37,6 @@ func NewTenant(tenantID, name, description string) *Tenant {
         TenantID:   tenantID,
         Name:       name,
         Description: description,
+        Status:     TenantStatusActive,
     }
 }
\ No newline at end of file
@@ -2,35 +2,154 @@ package models
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/himanshum9/go-mithril/pkg/apierror"
+	"github.com/himanshum9/go-mithril/pkg/pagination"
+	"github.com/himanshum9/go-mithril/pkg/validation"
 )
 
+// Sort fields accepted by ListTenants, mapped to their columns
+var tenantSortColumns = map[string]string{
+	"name":       "name",
+	"created_at": "created_at",
+}
+
+// TenantSortFields lists the accepted sort values, default first
+var TenantSortFields = []string{"name", "created_at"}
+
+// ListTenantsOptions filters and pages a tenant listing
+type ListTenantsOptions struct {
+	pagination.Params
+	NamePrefix string
+	Status     string
+}
+
+// ListTenantsOptionsFromQuery reads ?limit=&cursor=&sort=&order=&q=&status=
+func ListTenantsOptionsFromQuery(q url.Values) (ListTenantsOptions, error) {
+	params, err := pagination.ParseQuery(q, TenantSortFields...)
+	if err != nil {
+		return ListTenantsOptions{}, err
+	}
+	opts := ListTenantsOptions{Params: params, NamePrefix: q.Get("q"), Status: q.Get("status")}
+	v := validation.New()
+	v.OneOf("status", opts.Status, TenantStatusActive, TenantStatusSuspended)
+	return opts, v.Err()
+}
+
+// ListTenantsError returns the field errors of a failed tenant listing
+// caused by the request, or nil when the failure is the server's
+func ListTenantsError(err error) []apierror.FieldError {
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		return fields
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		return []apierror.FieldError{{Field: "cursor", Code: apierror.FieldInvalid, Message: "cursor was not issued by this listing"}}
+	}
+	return nil
+}
+
 func SaveTenant(ctx context.Context, t *Tenant) error {
-	_, err := DB.ExecContext(ctx, `INSERT INTO tenants (tenant_id, name, description) VALUES ($1, $2, $3)`, t.TenantID, t.Name, t.Description)
-	return err
+	if t.Status == "" {
+		t.Status = TenantStatusActive
+	}
+	return DB.QueryRowContext(ctx, `INSERT INTO tenants (tenant_id, name, description, status) VALUES ($1, $2, $3, $4) RETURNING created_at`, t.TenantID, t.Name, t.Description, t.Status).Scan(&t.CreatedAt)
 }
 
 func GetTenant(ctx context.Context, tenantID string) (*Tenant, error) {
-	row := DB.QueryRowContext(ctx, `SELECT tenant_id, name, description FROM tenants WHERE tenant_id = $1`, tenantID)
+	row := DB.QueryRowContext(ctx, `SELECT tenant_id, name, description, status, created_at FROM tenants WHERE tenant_id = $1`, tenantID)
 	var t Tenant
-	if err := row.Scan(&t.TenantID, &t.Name, &t.Description); err != nil {
+	if err := row.Scan(&t.TenantID, &t.Name, &t.Description, &t.Status, &t.CreatedAt); err != nil {
 		return nil, err
 	}
 	return &t, nil
 }
 
-func ListTenants(ctx context.Context) ([]Tenant, error) {
-	rows, err := DB.QueryContext(ctx, `SELECT tenant_id, name, description FROM tenants`)
+func ListTenants(ctx context.Context, opts ListTenantsOptions) (*pagination.Page[Tenant], error) {
+	sortColumn, ok := tenantSortColumns[opts.Sort]
+	if !ok {
+		sortColumn = "name"
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = pagination.DefaultLimit
+	}
+
+	var where []string
+	var args []interface{}
+	if opts.NamePrefix != "" {
+		args = append(args, escapeLike(opts.NamePrefix)+"%")
+		where = append(where, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+	if opts.Status != "" {
+		args = append(args, opts.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	// The total ignores the cursor so it stays stable while paging
+	countQuery := `SELECT COUNT(*) FROM tenants` + whereClause(where)
+	var total int
+	if err := DB.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	if opts.Cursor != nil {
+		var value interface{} = opts.Cursor.Value
+		if sortColumn == "created_at" {
+			ts, err := time.Parse(time.RFC3339Nano, opts.Cursor.Value)
+			if err != nil {
+				return nil, pagination.ErrInvalidCursor
+			}
+			value = ts
+		}
+		args = append(args, value, opts.Cursor.ID)
+		where = append(where, fmt.Sprintf("(%s, tenant_id) %s ($%d, $%d)", sortColumn, opts.Comparator(), len(args)-1, len(args)))
+	}
+
+	args = append(args, opts.Limit+1)
+	query := fmt.Sprintf(`SELECT tenant_id, name, description, status, created_at FROM tenants%s ORDER BY %s %s, tenant_id %s LIMIT $%d`,
+		whereClause(where), sortColumn, opts.Direction(), opts.Direction(), len(args))
+	rows, err := DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var tenants []Tenant
+	tenants := []Tenant{}
 	for rows.Next() {
 		var t Tenant
-		if err := rows.Scan(&t.TenantID, &t.Name, &t.Description); err != nil {
+		if err := rows.Scan(&t.TenantID, &t.Name, &t.Description, &t.Status, &t.CreatedAt); err != nil {
 			return nil, err
 		}
 		tenants = append(tenants, t)
 	}
-	return tenants, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &pagination.Page[Tenant]{Items: tenants, Total: &total}
+	if len(tenants) > opts.Limit {
+		page.Items = tenants[:opts.Limit]
+		last := page.Items[len(page.Items)-1]
+		cursor := pagination.Cursor{Value: last.Name, ID: last.TenantID}
+		if sortColumn == "created_at" {
+			cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+		}
+		page.NextCursor = pagination.EncodeCursor(cursor)
+	}
+	return page, nil
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }